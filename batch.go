@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/govau/sdget/txtkv"
+	"github.com/pkg/errors"
+)
+
+// A query from one line of batch input.  Plain lines look like "[--type=T] [--format=F] [--as=A] source key
+// [default...]", and JSON lines are objects with these fields.
+type batchQuery struct {
+	Source   string   `json:"source"`
+	Sources  []string `json:"sources"`
+	Key      string   `json:"key"`
+	Defaults []string `json:"defaults"`
+	Type     string   `json:"type"`
+	Format   string   `json:"format"`
+	As       string   `json:"as"`
+}
+
+type batchResult struct {
+	line    int
+	query   *batchQuery
+	options *options
+	values  []string
+	err     *stepError
+}
+
+func parseBatchLine(line string) (*batchQuery, error) {
+	query := &batchQuery{}
+	if strings.HasPrefix(line, "{") {
+		decoder := json.NewDecoder(strings.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(query); err != nil {
+			return nil, errors.Wrap(err, "invalid JSON query")
+		}
+	} else {
+		fields := strings.Fields(line)
+		for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+			name, value, ok := strings.Cut(fields[0][2:], "=")
+			if !ok {
+				return nil, errors.Errorf("option %s needs a value, like --%s=VALUE", fields[0], name)
+			}
+			switch name {
+			case "type":
+				query.Type = value
+			case "format":
+				query.Format = value
+			case "as":
+				query.As = value
+			default:
+				return nil, errors.Errorf("unknown option %s (only --type, --format and --as can be used in batch queries)", fields[0])
+			}
+			fields = fields[1:]
+		}
+		if len(fields) > 0 {
+			query.Source = fields[0]
+		}
+		if len(fields) > 1 {
+			query.Key = fields[1]
+		}
+		if len(fields) > 2 {
+			query.Defaults = fields[2:]
+		}
+	}
+
+	if query.Source != "" && len(query.Sources) > 0 {
+		return nil, errors.New("only one of \"source\" and \"sources\" can be given")
+	}
+	if query.Source != "" {
+		query.Sources = []string{query.Source}
+		query.Source = ""
+	}
+	if len(query.Sources) == 0 || query.Key == "" {
+		return nil, errors.New("a source and a key are required")
+	}
+	if query.Type != "" && query.Type != "single" && query.Type != "list" {
+		return nil, errors.Errorf("invalid type \"%s\" (single, list)", query.Type)
+	}
+	if query.Format != "" && query.Format != "json" && query.Format != "plain" {
+		return nil, errors.Errorf("invalid format \"%s\" (json, plain)", query.Format)
+	}
+	if query.As != "" && !isDecodeType(query.As) {
+		return nil, errors.Errorf("invalid --as type \"%s\" (%s)", query.As, strings.Join(decodeTypes, ", "))
+	}
+	return query, nil
+}
+
+func isDecodeType(as string) bool {
+	for _, decodeType := range decodeTypes {
+		if as == decodeType {
+			return true
+		}
+	}
+	return false
+}
+
+// The options for one query, with its own type, format and --as type overriding the global ones
+func (q *batchQuery) options(global *options) *options {
+	result := *global
+	if q.Type != "" {
+		result.valueType = q.Type
+	}
+	if q.Format != "" {
+		result.outputFormat = q.Format
+	}
+	if q.As != "" {
+		result.as = q.As
+	}
+	return &result
+}
+
+func runBatchQuery(global *options, client *txtkv.Client, line int, text string) *batchResult {
+	result := &batchResult{line: line, options: global}
+	query, err := parseBatchLine(text)
+	if err != nil {
+		result.err = &stepError{classUsage, "parsing", err}
+		return result
+	}
+	result.query = query
+	result.options = query.options(global)
+
+	if result.options.valueType == "single" && len(query.Defaults) > 1 {
+		result.err = &stepError{classUsage, "parsing", errors.Errorf("got %d default values, but the value type is \"single\"", len(query.Defaults))}
+		return result
+	}
+	query.Sources, err = expandAliases(global.config, query.Sources)
+	if err != nil {
+		result.err = &stepError{classConfig, "parsing", err}
+		return result
+	}
+	table, err := client.LayeredTable(context.Background(), query.Sources, global.policy)
+	if err != nil {
+		result.err = &stepError{classSource, "fetching", err}
+		return result
+	}
+	if global.schema != nil {
+		if err := global.schema.Validate(table); err != nil {
+			result.err = &stepError{classSchema, "validating", err}
+			return result
+		}
+	}
+	result.values, result.err = getValues(result.options, table, query.Key, query.Defaults)
+	return result
+}
+
+// batchErrorLine is the plain output of a failed query.  Escaped values can't look like it, since backslashes in them
+// are doubled.
+const batchErrorLine = `\error`
+
+// Backslashes, tabs and line breaks in plain values are escaped, so that each result is one line of tab-separated
+// values
+var batchValueEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// JSON results are objects with the line number, source(s), key and either the value(s) or an error.  Plain results
+// are the escaped value, or the escaped values separated by tabs (with --type list).  Failed queries have a
+// batchErrorLine, with the error on errSink.
+func outputBatchResult(sink io.Writer, errSink io.Writer, errorFormat string, result *batchResult) error {
+	var sourceList, key string
+	if result.query != nil {
+		sourceList, key = strings.Join(result.query.Sources, ", "), result.query.Key
+	}
+
+	var report *errorReport
+	if result.err != nil {
+		r := makeErrorReport(result.err.err, result.err.class, sourceList, key, "")
+		r.Line = result.line
+		report = &r
+	}
+
+	if result.options.outputFormat == "json" {
+		output := struct {
+			Line   int          `json:"line"`
+			Source string       `json:"source,omitempty"`
+			Key    string       `json:"key,omitempty"`
+			Value  interface{}  `json:"value,omitempty"`
+			Error  *errorReport `json:"error,omitempty"`
+		}{Line: result.line, Source: sourceList, Key: key, Error: report}
+		if report == nil {
+			natives := []interface{}{}
+			for _, value := range result.values {
+				native, err := nativeValue(result.options.as, value)
+				if err != nil {
+					return err
+				}
+				natives = append(natives, native)
+			}
+			output.Value = natives
+			if result.options.valueType == "single" {
+				output.Value = natives[0]
+			}
+		}
+		encoder := json.NewEncoder(sink)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(output); err != nil {
+			return errors.Wrap(err, "error writing JSON")
+		}
+		return nil
+	}
+
+	if report != nil {
+		if errorFormat == "json" {
+			encoder := json.NewEncoder(errSink)
+			encoder.SetEscapeHTML(false)
+			encoder.Encode(report)
+		} else {
+			fmt.Fprintf(errSink, "Line %d (%s): %s\n", result.line, report.Class, report.Detail)
+		}
+		_, err := fmt.Fprintln(sink, batchErrorLine)
+		return err
+	}
+	values := make([]string, len(result.values))
+	for i, value := range result.values {
+		values[i] = batchValueEscaper.Replace(value)
+	}
+	_, err := fmt.Fprintln(sink, strings.Join(values, "\t"))
+	return err
+}
+
+// How many queries can be waiting for their turn to be output, for each concurrent query
+const batchWindowPerQuery = 4
+
+// Queries are read and run as the input arrives, so that batch can be driven by a pipe or another process.  They run
+// concurrently, but results are output in the same order as the queries, so a slow query holds up the output of the
+// ones after it, up to a limit.  Blank lines and lines starting with "#" are skipped.
+func runBatch(options *options, input io.Reader, concurrency int) {
+	type batchJob struct {
+		number int
+		text   string
+		result chan *batchResult
+	}
+	jobs := make(chan *batchJob)
+	// Jobs in input order, waiting to be output.  The buffer bounds how far ahead of the output the input is read.
+	pending := make(chan *batchJob, batchWindowPerQuery*concurrency)
+	var readErr error
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		scanner := bufio.NewScanner(input)
+		scanner.Buffer(nil, 1024*1024)
+		for number := 1; scanner.Scan(); number++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			job := &batchJob{number, text, make(chan *batchResult, 1)}
+			pending <- job
+			jobs <- job
+		}
+		readErr = scanner.Err()
+	}()
+
+	// Each source is fetched once, and shared by all the queries that use it
+	clientOptions := options.client
+	clientOptions.ReuseFetches = true
+	client := txtkv.NewClient(clientOptions)
+	for worker := 0; worker < concurrency; worker++ {
+		go func() {
+			for job := range jobs {
+				job.result <- runBatchQuery(options, client, job.number, job.text)
+			}
+		}()
+	}
+
+	var firstFailure *stepError
+	for job := range pending {
+		result := <-job.result
+		if result.err != nil && firstFailure == nil {
+			firstFailure = result.err
+		}
+		if err := outputBatchResult(os.Stdout, os.Stderr, options.errorFormat, result); err != nil {
+			fail(options, classOutput, err, "", "", "Error writing output values: %s\n", err.Error())
+		}
+	}
+	if readErr != nil {
+		fail(options, classUsage, readErr, "", "", "Error reading batch queries: %s\n", readErr.Error())
+	}
+	if firstFailure != nil {
+		os.Exit(classifyError(firstFailure.err, firstFailure.class).code)
+	}
+}
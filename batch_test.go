@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/govau/sdget/txtkv"
+)
+
+type parseBatchLineTestPair struct {
+	Line  string
+	Query *batchQuery
+	Err   error
+}
+
+func TestParseBatchLine(t *testing.T) {
+	for _, testPair := range []parseBatchLineTestPair{
+		{"foo.example.com key", &batchQuery{Sources: []string{"foo.example.com"}, Key: "key"}, nil},
+		{"  foo.example.com\tkey a b ", &batchQuery{Sources: []string{"foo.example.com"}, Key: "key", Defaults: []string{"a", "b"}}, nil},
+		{"--type=list --format=json --as=int foo.example.com key", &batchQuery{Sources: []string{"foo.example.com"}, Key: "key", Type: "list", Format: "json", As: "int"}, nil},
+		{`{"source": "foo.example.com", "key": "key", "defaults": ["a b"]}`, &batchQuery{Sources: []string{"foo.example.com"}, Key: "key", Defaults: []string{"a b"}}, nil},
+		{`{"sources": ["a.example.com", "b.example.com"], "key": "key", "type": "list"}`, &batchQuery{Sources: []string{"a.example.com", "b.example.com"}, Key: "key", Type: "list"}, nil},
+		{"foo.example.com", nil, errors.New("No key")},
+		{"--type=list", nil, errors.New("No source")},
+		{"--type list foo.example.com key", nil, errors.New("Option without value")},
+		{"--policy=merge foo.example.com key", nil, errors.New("Unknown option")},
+		{"--type=many foo.example.com key", nil, errors.New("Invalid type")},
+		{"--format=zero foo.example.com key", nil, errors.New("Invalid format")},
+		{"--as=date foo.example.com key", nil, errors.New("Invalid --as type")},
+		{`{"source": "a.example.com", "sources": ["b.example.com"], "key": "key"}`, nil, errors.New("Source and sources")},
+		{`{"source": "foo.example.com", "key": "key", "policy": "merge"}`, nil, errors.New("Unknown field")},
+		{`{"source": "foo.example.com"`, nil, errors.New("Invalid JSON")},
+	} {
+		query, err := parseBatchLine(testPair.Line)
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if !reflect.DeepEqual(query, testPair.Query) {
+			t.Error("Expected", testPair.Query, "but got", query, "for", testPair)
+		}
+	}
+}
+
+type outputBatchResultTestPair struct {
+	Result      *batchResult
+	ErrorFormat string
+	Output      string
+	ErrOutput   string
+}
+
+func TestOutputBatchResult(t *testing.T) {
+	query := &batchQuery{Sources: []string{"a.example.com", "b.example.com"}, Key: "port"}
+	plain := &options{outputFormat: "plain", valueType: "single"}
+	list := &options{outputFormat: "plain", valueType: "list"}
+	json := &options{outputFormat: "json", valueType: "single", as: "int"}
+	jsonList := &options{outputFormat: "json", valueType: "list"}
+	notFound := &stepError{classKeyNotFound, "looking up", &txtkv.KeyError{Key: "port", Err: txtkv.ErrKeyNotFound}}
+
+	for _, testPair := range []outputBatchResultTestPair{
+		{&batchResult{3, query, plain, []string{"8443"}, nil}, "plain", "8443\n", ""},
+		{&batchResult{3, query, list, []string{"a", "b"}, nil}, "plain", "a\tb\n", ""},
+		{&batchResult{3, query, list, []string{"a\tb", `c\d`, "e\nf", ""}, nil}, "plain", "a\\tb\tc\\\\d\te\\nf\t\n", ""},
+		{&batchResult{3, query, list, []string{}, nil}, "plain", "\n", ""},
+		{&batchResult{3, query, plain, nil, notFound}, "plain", `\error` + "\n", "Line 3 (key-not-found): no values found for key port\n"},
+		{&batchResult{3, query, plain, nil, notFound}, "json", `\error` + "\n",
+			`{"code":4,"class":"key-not-found","source":"a.example.com, b.example.com","key":"port","detail":"no values found for key port","line":3}` + "\n"},
+		{&batchResult{3, nil, plain, nil, &stepError{classUsage, "parsing", errors.New("a source and a key are required")}}, "plain", `\error` + "\n", "Line 3 (usage): a source and a key are required\n"},
+		{&batchResult{3, query, json, []string{"8443"}, nil}, "plain", `{"line":3,"source":"a.example.com, b.example.com","key":"port","value":8443}` + "\n", ""},
+		{&batchResult{3, query, jsonList, []string{""}, nil}, "plain", `{"line":3,"source":"a.example.com, b.example.com","key":"port","value":[""]}` + "\n", ""},
+		{&batchResult{3, query, json, nil, notFound}, "plain",
+			`{"line":3,"source":"a.example.com, b.example.com","key":"port","error":{"code":4,"class":"key-not-found","source":"a.example.com, b.example.com","key":"port","detail":"no values found for key port","line":3}}` + "\n", ""},
+	} {
+		var output, errOutput bytes.Buffer
+		if err := outputBatchResult(&output, &errOutput, testPair.ErrorFormat, testPair.Result); err != nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if output.String() != testPair.Output || errOutput.String() != testPair.ErrOutput {
+			t.Error("Expected", testPair.Output, testPair.ErrOutput, "but got", output.String(), errOutput.String(), "for", testPair)
+		}
+	}
+}
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/govau/sdget/internal/yamlnode"
+	"github.com/pkg/errors"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config files have defaults for flags, named profiles that override them, and source aliases:
+//
+//	defaults:
+//	  format: json
+//	  batch:
+//	    concurrency: 16
+//	aliases:
+//	  app: dns://10.0.0.2/app.prod.example.com
+//	profiles:
+//	  staging:
+//	    nameserver: 10.1.0.2
+//	    aliases:
+//	      app: dns://10.1.0.2/app.staging.example.com
+//
+// Settings are flag names (without "--"), or a command name with settings for that command's flags.  Flags override
+// environment variables, which override the selected profile, which overrides the defaults.
+type config struct {
+	files    []string
+	defaults map[string]configSetting
+	aliases  map[string]configSetting
+	profiles map[string]*configProfile
+}
+
+type configProfile struct {
+	settings map[string]configSetting
+	aliases  map[string]configSetting
+}
+
+// A flag setting or alias, with where it came from.  Names of command flags are "command.flag".
+type configSetting struct {
+	values []string
+	origin string
+	line   int
+}
+
+// The system config file comes first, so that the user's can override it
+func defaultConfigPaths() []string {
+	paths := []string{"/etc/sdget/config.yaml"}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "sdget", "config.yaml"))
+	}
+	return paths
+}
+
+// loadConfig reads and merges config files.  Missing files are skipped, unless they were asked for explicitly.
+func loadConfig(paths []string, explicit bool) (*config, error) {
+	result := &config{
+		defaults: make(map[string]configSetting),
+		aliases:  make(map[string]configSetting),
+		profiles: make(map[string]*configProfile),
+	}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) && !explicit {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading config file")
+		}
+		if err := result.add(path, string(data)); err != nil {
+			return nil, errors.Wrapf(err, "error in config file %s", path)
+		}
+		result.files = append(result.files, path)
+	}
+	return result, nil
+}
+
+func (c *config) add(path string, data string) error {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &document); err != nil {
+		return err
+	}
+	if len(document.Content) == 0 {
+		// An empty file
+		return nil
+	}
+	root := yamlnode.ResolveAlias(document.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return errors.Errorf("line %d: expected a mapping with defaults, aliases and profiles", root.Line)
+	}
+	return yamlnode.ForEachPair(root, func(key string, node *yaml.Node) error {
+		switch key {
+		case "defaults":
+			return addSettings(c.defaults, node, "", "config file "+path)
+		case "aliases":
+			return addAliases(c.aliases, node, "config file "+path)
+		case "profiles":
+			if node.Kind != yaml.MappingNode {
+				return errors.Errorf("line %d: profiles must be a mapping of names to settings", node.Line)
+			}
+			return yamlnode.ForEachPair(node, func(name string, profileNode *yaml.Node) error {
+				if profileNode.Kind != yaml.MappingNode {
+					return errors.Errorf("line %d: profile %s must be a mapping of settings", profileNode.Line, name)
+				}
+				// A profile in a later file replaces the whole profile
+				profile := &configProfile{
+					settings: make(map[string]configSetting),
+					aliases:  make(map[string]configSetting),
+				}
+				origin := fmt.Sprintf("profile %s in %s", name, path)
+				if err := yamlnode.ForEachPair(profileNode, func(settingKey string, settingNode *yaml.Node) error {
+					if settingKey == "aliases" {
+						return addAliases(profile.aliases, settingNode, origin)
+					}
+					return addSetting(profile.settings, settingKey, settingNode, "", origin)
+				}); err != nil {
+					return err
+				}
+				c.profiles[name] = profile
+				return nil
+			})
+		default:
+			return errors.Errorf("line %d: unknown section \"%s\" (defaults, aliases, profiles)", node.Line, key)
+		}
+	})
+}
+
+func addSettings(settings map[string]configSetting, node *yaml.Node, command string, origin string) error {
+	if node.Kind != yaml.MappingNode {
+		return errors.Errorf("line %d: expected a mapping of flag names to values", node.Line)
+	}
+	return yamlnode.ForEachPair(node, func(key string, value *yaml.Node) error {
+		return addSetting(settings, key, value, command, origin)
+	})
+}
+
+// A mapping is the settings of a command's flags
+func addSetting(settings map[string]configSetting, key string, node *yaml.Node, command string, origin string) error {
+	if node.Kind == yaml.MappingNode {
+		if command != "" {
+			return errors.Errorf("line %d: commands don't have subcommands with flags", node.Line)
+		}
+		return addSettings(settings, node, key, origin)
+	}
+	name := key
+	if command != "" {
+		name = command + "." + key
+	}
+	values, err := yamlValues(node)
+	if err != nil {
+		return err
+	}
+	settings[name] = configSetting{values: values, origin: origin, line: node.Line}
+	return nil
+}
+
+func addAliases(aliases map[string]configSetting, node *yaml.Node, origin string) error {
+	if node.Kind != yaml.MappingNode {
+		return errors.Errorf("line %d: aliases must be a mapping of names to sources", node.Line)
+	}
+	return yamlnode.ForEachPair(node, func(name string, source *yaml.Node) error {
+		if source.Kind != yaml.ScalarNode || source.Value == "" {
+			return errors.Errorf("line %d: alias %s must be a single source", source.Line, name)
+		}
+		aliases[strings.TrimPrefix(name, "@")] = configSetting{values: []string{source.Value}, origin: origin, line: source.Line}
+		return nil
+	})
+}
+
+// The values of a scalar, or a list of scalars.  A null value (like "key:") is no values, the same as an empty list.
+func yamlValues(node *yaml.Node) ([]string, error) {
+	switch {
+	case node.Kind == yaml.ScalarNode && node.Tag == "!!null":
+		return []string{}, nil
+	case node.Kind == yaml.ScalarNode:
+		return []string{node.Value}, nil
+	case node.Kind == yaml.SequenceNode:
+		values := []string{}
+		for _, item := range node.Content {
+			item = yamlnode.ResolveAlias(item)
+			if item.Kind != yaml.ScalarNode {
+				return nil, errors.Errorf("line %d: lists can only have strings", item.Line)
+			}
+			values = append(values, item.Value)
+		}
+		return values, nil
+	default:
+		return nil, errors.Errorf("line %d: expected a string or a list of strings", node.Line)
+	}
+}
+
+// resolve combines the defaults with a profile (if any)
+func (c *config) resolve(profileName string) (settings map[string]configSetting, aliases map[string]configSetting, err error) {
+	settings = make(map[string]configSetting)
+	aliases = make(map[string]configSetting)
+	for name, setting := range c.defaults {
+		settings[name] = setting
+	}
+	for name, alias := range c.aliases {
+		aliases[name] = alias
+	}
+	if profileName == "" {
+		return settings, aliases, nil
+	}
+	profile, ok := c.profiles[profileName]
+	if !ok {
+		var names []string
+		for name := range c.profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, nil, errors.Errorf("unknown profile \"%s\" (profiles: %s)", profileName, strings.Join(names, ", "))
+	}
+	for name, setting := range profile.settings {
+		settings[name] = setting
+	}
+	for name, alias := range profile.aliases {
+		aliases[name] = alias
+	}
+	return settings, aliases, nil
+}
+
+// prescanFlag finds the value of a flag before the command line is parsed, falling back to its environment variable
+func prescanFlag(args []string, name string, envar string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--"+name && i+1 < len(args) {
+			return args[i+1]
+		}
+		if value, ok := strings.CutPrefix(arg, "--"+name+"="); ok {
+			return value
+		}
+	}
+	return os.Getenv(envar)
+}
+
+// configFlag finds the flag for a setting name
+func configFlag(app *kingpin.Application, name string) (*kingpin.FlagClause, error) {
+	command, flagName, isCommandFlag := strings.Cut(name, ".")
+	if !isCommandFlag {
+		flagName = command
+	}
+	if flagName == "help" || flagName == "version" || flagName == "config" || flagName == "profile" {
+		return nil, errors.Errorf("--%s can't be set in config files", flagName)
+	}
+	var flag *kingpin.FlagClause
+	if isCommandFlag {
+		cmd := app.GetCommand(command)
+		if cmd == nil {
+			return nil, errors.Errorf("unknown command \"%s\"", command)
+		}
+		flag = cmd.GetFlag(flagName)
+	} else {
+		flag = app.GetFlag(flagName)
+	}
+	if flag == nil || flag.Model().Hidden {
+		return nil, errors.Errorf("unknown flag --%s", flagName)
+	}
+	if flag.Model().Required {
+		return nil, errors.Errorf("--%s is required, so it can't have a default", flagName)
+	}
+	return flag, nil
+}
+
+// The configuration that was applied to the command line, for config show and for aliases in sources
+type appliedConfig struct {
+	files    []string
+	profile  string
+	settings map[string]configSetting
+	aliases  map[string]configSetting
+}
+
+// applyConfig loads the config files (or the --config file), and makes the settings of the defaults and --profile the
+// default values of their flags, so that flags and environment variables still override them.  Arguments that are
+// exactly "@alias" are replaced by the alias's source, because kingpin would otherwise read them as argument files.
+func applyConfig(app *kingpin.Application, args []string) ([]string, *appliedConfig, error) {
+	paths, explicit := defaultConfigPaths(), false
+	if path := prescanFlag(args, "config", "SDGET_CONFIG"); path != "" {
+		paths, explicit = []string{path}, true
+	}
+	loaded, err := loadConfig(paths, explicit)
+	if err != nil {
+		return args, nil, err
+	}
+	applied := &appliedConfig{
+		files:   loaded.files,
+		profile: prescanFlag(args, "profile", "SDGET_PROFILE"),
+	}
+	applied.settings, applied.aliases, err = loaded.resolve(applied.profile)
+	if err != nil {
+		return args, nil, err
+	}
+	for name, setting := range applied.settings {
+		flag, err := configFlag(app, name)
+		if err != nil {
+			return args, nil, errors.Wrapf(err, "line %d (%s)", setting.line, setting.origin)
+		}
+		flag.Default(setting.values...)
+	}
+
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = arg
+		name, ok := strings.CutPrefix(arg, "@")
+		if !ok {
+			continue
+		}
+		if alias, ok := applied.aliases[name]; ok {
+			expanded[i] = alias.values[0]
+		} else if _, err := os.Stat(name); os.IsNotExist(err) {
+			return args, nil, errors.Errorf("unknown source alias %s", arg)
+		}
+	}
+	return expanded, applied, nil
+}
+
+// expandAliases replaces "@alias" sources with their sources.  A nil config has no aliases.
+func expandAliases(applied *appliedConfig, sources []string) ([]string, error) {
+	var result []string
+	for _, source := range sources {
+		if name, ok := strings.CutPrefix(source, "@"); ok {
+			var alias configSetting
+			if applied != nil {
+				alias, ok = applied.aliases[name]
+			}
+			if !ok {
+				return nil, errors.Errorf("unknown source alias %s", source)
+			}
+			source = alias.values[0]
+		}
+		result = append(result, source)
+	}
+	return result, nil
+}
+
+type configEntry struct {
+	Name   string   `json:"name"`
+	Value  []string `json:"value"`
+	Origin string   `json:"origin"`
+}
+
+// configEntries lists the effective value of every flag, and where it came from.  args are the (alias-expanded)
+// command line arguments, for finding which flags were given.
+func configEntries(app *kingpin.Application, applied *appliedConfig, args []string, selected string) ([]configEntry, error) {
+	context, err := app.ParseContext(args)
+	if err != nil {
+		return nil, err
+	}
+	given := make(map[*kingpin.FlagClause]bool)
+	for _, element := range context.Elements {
+		if flag, ok := element.Clause.(*kingpin.FlagClause); ok {
+			given[flag] = true
+		}
+	}
+
+	entry := func(name string, flag *kingpin.FlagClause, isSet bool) configEntry {
+		model := flag.Model()
+		result := configEntry{Name: name, Value: model.Default, Origin: "default"}
+		if setting, ok := applied.settings[name]; ok {
+			result.Value, result.Origin = setting.values, setting.origin
+		}
+		switch {
+		case given[flag]:
+			result.Origin = "command line"
+		case model.Envar != "" && os.Getenv(model.Envar) != "":
+			result.Origin = "environment variable " + model.Envar
+		}
+		// Flags of other commands haven't been set, so only have their default values
+		if isSet {
+			result.Value = []string{model.Value.String()}
+			// Repeatable flags have lists of values
+			if getter, ok := model.Value.(kingpin.Getter); ok {
+				if values, ok := getter.Get().(*[]string); ok {
+					result.Value = *values
+				}
+			}
+		}
+		if result.Value == nil {
+			result.Value = []string{}
+		}
+		return result
+	}
+
+	var entries []configEntry
+	for _, model := range app.Model().Flags {
+		if model.Hidden || model.Name == "help" || model.Name == "version" || model.Name == "config" || model.Name == "profile" {
+			continue
+		}
+		entries = append(entries, entry(model.Name, app.GetFlag(model.Name), true))
+	}
+	for _, command := range app.Model().FlattenedCommands() {
+		cmd := app.GetCommand(command.Name)
+		if cmd == nil {
+			// Subcommands (like config show) have no flags
+			continue
+		}
+		for _, model := range command.Flags {
+			if model.Required {
+				continue
+			}
+			entries = append(entries, entry(command.Name+"."+model.Name, cmd.GetFlag(model.Name), command.FullCommand == selected))
+		}
+	}
+	return entries, nil
+}
+
+// JSON output is an object with the config files, profile, flags and aliases.  Otherwise, the output is YAML that could
+// be used as a config file, with comments saying where each value came from.
+func outputConfig(options *options, sink io.Writer, applied *appliedConfig, entries []configEntry) error {
+	var aliases []configEntry
+	for name, alias := range applied.aliases {
+		aliases = append(aliases, configEntry{Name: name, Value: alias.values, Origin: alias.origin})
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Name < aliases[j].Name })
+
+	if options.outputFormat == "json" {
+		result := struct {
+			Files   []string      `json:"files"`
+			Profile string        `json:"profile,omitempty"`
+			Flags   []configEntry `json:"flags"`
+			Aliases []configEntry `json:"aliases"`
+		}{applied.files, applied.profile, entries, aliases}
+		if result.Files == nil {
+			result.Files = []string{}
+		}
+		if result.Aliases == nil {
+			result.Aliases = []configEntry{}
+		}
+		encoder := json.NewEncoder(sink)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return errors.Wrap(encoder.Encode(result), "error writing JSON")
+	}
+
+	files := "none"
+	if len(applied.files) > 0 {
+		files = strings.Join(applied.files, ", ")
+	}
+	comment := "Config files: " + files
+	if applied.profile != "" {
+		comment += "\nProfile: " + applied.profile
+	}
+	defaults := &yaml.Node{Kind: yaml.MappingNode}
+	root := &yaml.Node{Kind: yaml.MappingNode, HeadComment: comment}
+	root.Content = append(root.Content, yamlScalar("defaults", ""), defaults)
+	var commandNode *yaml.Node
+	command := ""
+	for _, entry := range entries {
+		parent, name := defaults, entry.Name
+		if entryCommand, flagName, ok := strings.Cut(entry.Name, "."); ok {
+			if entryCommand != command {
+				commandNode = &yaml.Node{Kind: yaml.MappingNode}
+				defaults.Content = append(defaults.Content, yamlScalar(entryCommand, ""), commandNode)
+				command = entryCommand
+			}
+			parent, name = commandNode, flagName
+		}
+		parent.Content = append(parent.Content, yamlScalar(name, ""), yamlValue(entry.Value, entry.Origin))
+	}
+	if len(aliases) > 0 {
+		aliasesNode := &yaml.Node{Kind: yaml.MappingNode}
+		for _, alias := range aliases {
+			aliasesNode.Content = append(aliasesNode.Content, yamlScalar(alias.Name, ""), yamlScalar(alias.Value[0], alias.Origin))
+		}
+		root.Content = append(root.Content, yamlScalar("aliases", ""), aliasesNode)
+	}
+
+	encoder := yaml.NewEncoder(sink)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return errors.Wrap(err, "error writing YAML")
+	}
+	return errors.Wrap(encoder.Close(), "error writing YAML")
+}
+
+// The encoder quotes values that need it
+func yamlScalar(value string, comment string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value, LineComment: comment}
+}
+
+// Repeatable flags are lists, and other flags are a single value
+func yamlValue(values []string, comment string) *yaml.Node {
+	if len(values) == 1 {
+		return yamlScalar(values[0], comment)
+	}
+	list := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle, LineComment: comment}
+	for _, value := range values {
+		list.Content = append(list.Content, yamlScalar(value, ""))
+	}
+	return list
+}
+
+func resolveAliases(options *options, sources []string) []string {
+	sources, err := expandAliases(options.config, sources)
+	if err != nil {
+		fail(options, classConfig, err, "", "", "Error looking up source alias: %s\n", err.Error())
+	}
+	return sources
+}
+
+func runConfigShow(options *options, args []string, command string) {
+	entries, err := configEntries(kingpin.CommandLine, options.config, args, command)
+	if err != nil {
+		fail(options, classUsage, err, "", "", "Error reading command line: %s\n", err.Error())
+	}
+	if err := outputConfig(options, os.Stdout, options.config, entries); err != nil {
+		fail(options, classOutput, err, "", "", "Error writing configuration: %s\n", err.Error())
+	}
+}
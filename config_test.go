@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func writeTestConfig(t *testing.T, name string, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	return path
+}
+
+type loadConfigTestPair struct {
+	Profile  string
+	Settings map[string][]string
+	Aliases  map[string]string
+}
+
+func TestLoadConfig(t *testing.T) {
+	system := writeTestConfig(t, "system.yaml", `
+defaults:
+  format: json
+  nameserver: 10.0.0.1
+aliases:
+  app: dns://10.0.0.1/app.example.com
+  shared: dns://10.0.0.1/shared.example.com
+profiles:
+  prod:
+    nameserver: 10.0.0.2
+`)
+	user := writeTestConfig(t, "user.yaml", `
+defaults:
+  nameserver: 127.0.0.1
+  batch:
+    concurrency: 16
+  base-domain: |-
+    example.com
+profiles:
+  staging: &staging
+    nameserver: 10.1.0.2
+    verify-key: [a.pem, b.pem]
+    aliases:
+      "@app": dns://10.1.0.2/app.staging.example.com
+  staging-copy: *staging
+`)
+	loaded, err := loadConfig([]string{system, user, "/nonexistent/config.yaml"}, false)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if !reflect.DeepEqual(loaded.files, []string{system, user}) {
+		t.Error("Expected", []string{system, user}, "but got", loaded.files)
+	}
+
+	for _, testPair := range []loadConfigTestPair{
+		{
+			"",
+			map[string][]string{"format": {"json"}, "nameserver": {"127.0.0.1"}, "batch.concurrency": {"16"}, "base-domain": {"example.com"}},
+			map[string]string{"app": "dns://10.0.0.1/app.example.com", "shared": "dns://10.0.0.1/shared.example.com"},
+		},
+		{
+			"prod",
+			map[string][]string{"format": {"json"}, "nameserver": {"10.0.0.2"}, "batch.concurrency": {"16"}, "base-domain": {"example.com"}},
+			map[string]string{"app": "dns://10.0.0.1/app.example.com", "shared": "dns://10.0.0.1/shared.example.com"},
+		},
+		{
+			"staging",
+			map[string][]string{"format": {"json"}, "nameserver": {"10.1.0.2"}, "batch.concurrency": {"16"}, "base-domain": {"example.com"}, "verify-key": {"a.pem", "b.pem"}},
+			map[string]string{"app": "dns://10.1.0.2/app.staging.example.com", "shared": "dns://10.0.0.1/shared.example.com"},
+		},
+		{
+			"staging-copy",
+			map[string][]string{"format": {"json"}, "nameserver": {"10.1.0.2"}, "batch.concurrency": {"16"}, "base-domain": {"example.com"}, "verify-key": {"a.pem", "b.pem"}},
+			map[string]string{"app": "dns://10.1.0.2/app.staging.example.com", "shared": "dns://10.0.0.1/shared.example.com"},
+		},
+	} {
+		settings, aliases, err := loaded.resolve(testPair.Profile)
+		if err != nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+			continue
+		}
+		settingValues := make(map[string][]string)
+		for name, setting := range settings {
+			settingValues[name] = setting.values
+		}
+		if !reflect.DeepEqual(settingValues, testPair.Settings) {
+			t.Error("Expected", testPair.Settings, "but got", settingValues, "for", testPair)
+		}
+		aliasSources := make(map[string]string)
+		for name, alias := range aliases {
+			aliasSources[name] = alias.values[0]
+		}
+		if !reflect.DeepEqual(aliasSources, testPair.Aliases) {
+			t.Error("Expected", testPair.Aliases, "but got", aliasSources, "for", testPair)
+		}
+	}
+
+	if _, _, err := loaded.resolve("dev"); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+	if _, err := loadConfig([]string{"/nonexistent/config.yaml"}, true); err == nil {
+		t.Error("Expected error for missing explicit config file")
+	}
+}
+
+type configErrorTestPair struct {
+	Data string
+	Err  error
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, testPair := range []configErrorTestPair{
+		{"settings:\n  format: json\n", errors.New("Unknown section")},
+		{"defaults: json\n", errors.New("Defaults not a mapping")},
+		{"aliases:\n  app: [a, b]\n", errors.New("Alias with a list")},
+		{"aliases:\n  app:\n", errors.New("Empty alias")},
+		{"profiles:\n  prod: json\n", errors.New("Profile not a mapping")},
+		{"defaults:\n  batch:\n    concurrency:\n      x: 1\n", errors.New("Nested too deep")},
+		{"defaults:\n  format: json\n  format: plain\n", errors.New("Duplicate key")},
+		{"defaults:\n  source: [[a]]\n", errors.New("Nested list")},
+		{"defaults: [\n", errors.New("Invalid YAML")},
+	} {
+		err := (&config{
+			defaults: make(map[string]configSetting),
+			aliases:  make(map[string]configSetting),
+			profiles: make(map[string]*configProfile),
+		}).add("test.yaml", testPair.Data)
+
+		if err == nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+	}
+}
+
+type applyConfigTestPair struct {
+	Args       []string
+	Env        string
+	Nameserver string
+	Format     string
+	Source     string
+	Err        error
+}
+
+func TestApplyConfig(t *testing.T) {
+	path := writeTestConfig(t, "config.yaml", `
+defaults:
+  nameserver: 10.0.0.1
+  format: json
+aliases:
+  app: dns:app.example.com
+profiles:
+  prod:
+    nameserver: 10.0.0.2
+`)
+	for _, testPair := range []applyConfigTestPair{
+		{[]string{"@app"}, "", "10.0.0.1", "json", "dns:app.example.com", nil},
+		{[]string{"--profile", "prod", "@app"}, "", "10.0.0.2", "json", "dns:app.example.com", nil},
+		{[]string{"--profile=prod", "@app"}, "10.0.0.3", "10.0.0.3", "json", "dns:app.example.com", nil},
+		{[]string{"--profile=prod", "--nameserver", "10.0.0.4", "--format", "plain", "foo"}, "10.0.0.3", "10.0.0.4", "plain", "foo", nil},
+		{[]string{"--profile=dev", "foo"}, "", "", "", "", errors.New("Unknown profile")},
+		{[]string{"@nosuchalias"}, "", "", "", "", errors.New("Unknown alias")},
+	} {
+		t.Setenv("SDGET_TEST_NAMESERVER", testPair.Env)
+		app := kingpin.New("test", "")
+		app.Flag("config", "").String()
+		app.Flag("profile", "").String()
+		nameserver := app.Flag("nameserver", "").Envar("SDGET_TEST_NAMESERVER").String()
+		format := app.Flag("format", "").Default("plain").String()
+		source := app.Arg("source", "").String()
+
+		args, _, err := applyConfig(app, append([]string{"--config", path}, testPair.Args...))
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if err != nil {
+			continue
+		}
+		if _, err := app.Parse(args); err != nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+			continue
+		}
+		if *nameserver != testPair.Nameserver || *format != testPair.Format || *source != testPair.Source {
+			t.Error("Expected", testPair.Nameserver, testPair.Format, testPair.Source, "but got", *nameserver, *format, *source, "for", testPair)
+		}
+	}
+
+	// Settings for flags that don't exist are errors
+	badPath := writeTestConfig(t, "bad.yaml", "defaults:\n  nameservers: 10.0.0.1\n")
+	if _, _, err := applyConfig(kingpin.New("test", ""), []string{"--config=" + badPath}); err == nil {
+		t.Error("Expected error for unknown flag")
+	}
+}
+
+type expandAliasesTestPair struct {
+	Sources []string
+	Result  []string
+	Err     error
+}
+
+func TestExpandAliases(t *testing.T) {
+	applied := &appliedConfig{aliases: map[string]configSetting{"app": {values: []string{"dns:app.example.com"}}}}
+	for _, testPair := range []expandAliasesTestPair{
+		{[]string{"foo.example.com", "@app"}, []string{"foo.example.com", "dns:app.example.com"}, nil},
+		{[]string{"@other"}, nil, errors.New("Unknown alias")},
+	} {
+		result, err := expandAliases(applied, testPair.Sources)
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if !reflect.DeepEqual(result, testPair.Result) {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/govau/sdget/txtkv"
+	"github.com/pkg/errors"
+)
+
+func runCheckConsistency(options *options, domain string) {
+	client := txtkv.NewClient(options.client)
+	report, err := client.CheckConsistency(context.Background(), domain)
+	if err != nil {
+		fail(options, classSource, err, domain, "", "Error looking up authoritative nameservers:\n%+v\n", err.Error())
+	}
+
+	if err = outputConsistencyReport(options, os.Stdout, report); err != nil {
+		fail(options, classOutput, err, "", "", "Error writing consistency report: %s\n", err.Error())
+	}
+
+	if !report.Consistent() {
+		fail(options, classInconsistent, nil, domain, "", "")
+	}
+}
+
+func outputConsistencyReport(options *options, sink io.Writer, report *txtkv.ConsistencyReport) error {
+	if options.outputFormat == "json" {
+		type jsonServer struct {
+			Name          string   `json:"name"`
+			Address       string   `json:"address"`
+			Serial        uint32   `json:"serial"`
+			Status        string   `json:"status"`
+			DifferingKeys []string `json:"differing_keys,omitempty"`
+			Error         string   `json:"error,omitempty"`
+		}
+		jsonReport := struct {
+			Domain     string       `json:"domain"`
+			Zone       string       `json:"zone"`
+			Serial     uint32       `json:"serial"`
+			Consistent bool         `json:"consistent"`
+			Servers    []jsonServer `json:"servers"`
+		}{
+			Domain:     report.Domain,
+			Zone:       report.Zone,
+			Serial:     report.Serial,
+			Consistent: report.Consistent(),
+			Servers:    []jsonServer{},
+		}
+		for _, status := range report.Servers {
+			server := jsonServer{
+				Name:          status.Name,
+				Address:       status.Address,
+				Serial:        status.Serial,
+				Status:        status.Status,
+				DifferingKeys: status.DifferingKeys,
+			}
+			if status.Err != nil {
+				server.Error = status.Err.Error()
+			}
+			jsonReport.Servers = append(jsonReport.Servers, server)
+		}
+		encoder := json.NewEncoder(sink)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(jsonReport); err != nil {
+			return errors.Wrap(err, "error writing JSON")
+		}
+		return nil
+	}
+
+	terminator := "\n"
+	if options.outputFormat == "zero" {
+		terminator = "\000"
+	}
+	for _, status := range report.Servers {
+		// Nameservers without a known address still get a column for it
+		address := status.Address
+		if address == "" {
+			address = "-"
+		}
+		line := fmt.Sprintf("%s %s serial %d %s", status.Name, address, status.Serial, status.Status)
+		if status.Err != nil {
+			line = fmt.Sprintf("%s %s %s: %s", status.Name, address, status.Status, status.Err.Error())
+		} else if len(status.DifferingKeys) > 0 {
+			line = fmt.Sprintf("%s (differing keys: %s)", line, strings.Join(status.DifferingKeys, ", "))
+		}
+		if _, err := fmt.Fprint(sink, line, terminator); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/govau/sdget/txtkv"
+	"github.com/pkg/errors"
+)
+
+var decodeTypes = []string{"int", "float", "bool", "duration", "base64", "hex", "json", "url"}
+
+// Checks that values can be parsed as the --as type, and returns them in a normalised form.
+// Errors are of type *txtkv.ValueError.
+func decodeValues(as string, key string, values []string) ([]string, error) {
+	var result []string
+	for _, value := range values {
+		normalised, err := decodeValue(as, value)
+		if err != nil {
+			return nil, &txtkv.ValueError{Key: key, Value: value, Type: as, Err: err}
+		}
+		result = append(result, normalised)
+	}
+	return result, nil
+}
+
+func decodeValue(as string, value string) (string, error) {
+	switch as {
+	case "int":
+		result, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(result, 10), nil
+
+	case "float":
+		result, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", err
+		}
+		if math.IsInf(result, 0) || math.IsNaN(result) {
+			return "", errors.New("not a finite number")
+		}
+		return strconv.FormatFloat(result, 'g', -1, 64), nil
+
+	case "bool":
+		result, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(result), nil
+
+	case "duration":
+		result, err := time.ParseDuration(value)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+
+	case "base64":
+		// Padding and the URL-safe alphabet are both optional
+		for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if data, err := encoding.DecodeString(value); err == nil {
+				return base64.StdEncoding.EncodeToString(data), nil
+			}
+		}
+		return "", errors.New("invalid base64 data")
+
+	case "hex":
+		// Fingerprints are often written like AB:CD:EF
+		data, err := hex.DecodeString(strings.Replace(value, ":", "", -1))
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(data), nil
+
+	case "json":
+		var buffer bytes.Buffer
+		if err := json.Compact(&buffer, []byte(value)); err != nil {
+			return "", err
+		}
+		return buffer.String(), nil
+
+	case "url":
+		result, err := url.Parse(value)
+		if err != nil {
+			return "", err
+		}
+		if result.Scheme == "" {
+			return "", errors.New("not an absolute URL")
+		}
+		return result.String(), nil
+	}
+	return "", errors.Errorf("unknown type %s", as)
+}
+
+// Converts a normalised value to the type used in JSON output.  Numbers, booleans and JSON values are native, and
+// everything else is a string.
+func nativeValue(as string, value string) (interface{}, error) {
+	switch as {
+	case "int":
+		return strconv.ParseInt(value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(value, 64)
+	case "bool":
+		return strconv.ParseBool(value)
+	case "json":
+		return json.RawMessage(value), nil
+	}
+	return value, nil
+}
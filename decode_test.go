@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/govau/sdget/txtkv"
+)
+
+type decodeValueTestPair struct {
+	As     string
+	Value  string
+	Result string
+	Err    error
+}
+
+func TestDecodeValue(t *testing.T) {
+	for _, testPair := range []decodeValueTestPair{
+		{"int", "42", "42", nil},
+		{"int", "+007", "7", nil},
+		{"int", "-1", "-1", nil},
+		{"int", "4.2", "", errors.New("Not an int")},
+		{"int", " 42", "", errors.New("Not an int")},
+		{"float", "1e3", "1000", nil},
+		{"float", "0.50", "0.5", nil},
+		{"float", "inf", "", errors.New("Not finite")},
+		{"float", "NaN", "", errors.New("Not finite")},
+		{"bool", "1", "true", nil},
+		{"bool", "False", "false", nil},
+		{"bool", "yes", "", errors.New("Not a bool")},
+		{"duration", "90s", "1m30s", nil},
+		{"duration", "90", "", errors.New("No unit")},
+		{"base64", "aGVsbG8=", "aGVsbG8=", nil},
+		{"base64", "aGVsbG8", "aGVsbG8=", nil},
+		{"base64", "-_8=", "+/8=", nil},
+		{"base64", "not base64!", "", errors.New("Invalid base64")},
+		{"hex", "AB:CD:EF", "abcdef", nil},
+		{"hex", "00ff", "00ff", nil},
+		{"hex", "abc", "", errors.New("Odd length")},
+		{"json", `{ "a": [1, 2] }`, `{"a":[1,2]}`, nil},
+		{"json", `"<tag>"`, `"<tag>"`, nil},
+		{"json", `{"a":`, "", errors.New("Invalid JSON")},
+		{"url", "https://example.com/a%20b?c=d", "https://example.com/a%20b?c=d", nil},
+		{"url", "/relative/path", "", errors.New("Not absolute")},
+		{"url", "http://[::1", "", errors.New("Invalid URL")},
+	} {
+		result, err := decodeValue(testPair.As, testPair.Value)
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if result != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+
+	_, err := decodeValues("int", "port", []string{"80", "http"})
+	var valueError *txtkv.ValueError
+	if !errors.As(err, &valueError) || valueError.Value != "http" {
+		t.Error("Expected ValueError for http but got", err)
+	}
+}
+
+type outputDecodedTestPair struct {
+	As     string
+	Values []string
+	Result string
+}
+
+func TestOutputDecoded(t *testing.T) {
+	for _, testPair := range []outputDecodedTestPair{
+		{"int", []string{"1", "2"}, "[1,2]\n"},
+		{"float", []string{"0.5"}, "[0.5]\n"},
+		{"bool", []string{"true"}, "[true]\n"},
+		{"json", []string{`{"a":"<b>"}`}, `[{"a":"<b>"}]` + "\n"},
+		{"duration", []string{"1m30s"}, `["1m30s"]` + "\n"},
+		{"", []string{"1"}, `["1"]` + "\n"},
+	} {
+		options := &options{outputFormat: "json", valueType: "list", as: testPair.As}
+		var outBuffer bytes.Buffer
+		if err := output(options, &outBuffer, testPair.Values); err != nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+		if result := outBuffer.String(); result != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/govau/sdget/txtkv"
+	"github.com/pkg/errors"
+)
+
+func runBrowse(options *options, service string) {
+	client := txtkv.NewClient(options.client)
+	instances, err := client.Browse(context.Background(), service)
+	if err != nil {
+		fail(options, classSource, err, service, "", "Error browsing service %s:\n%+v\n", service, err.Error())
+	}
+	for _, instance := range instances {
+		checkSchema(options, instance.Table, instance.Name)
+	}
+
+	if err = outputInstances(options, os.Stdout, instances); err != nil {
+		fail(options, classOutput, err, "", "", "Error writing service instances: %s\n", err.Error())
+	}
+}
+
+func runResolve(options *options, name string, key string, defaultValues []string) {
+	if options.valueType == "single" && len(defaultValues) > 1 {
+		fail(options, classUsage, nil, name, key, "Got %d default values, but the value type is \"single\".  (Did you mean to set --type list?)\n", len(defaultValues))
+	}
+
+	client := txtkv.NewClient(options.client)
+	instance, err := client.ResolveInstance(context.Background(), name)
+	if err != nil {
+		fail(options, classSource, err, name, "", "Error resolving service instance %s:\n%+v\n", name, err.Error())
+	}
+	checkSchema(options, instance.Table, name)
+
+	if key == "" {
+		err = outputInstances(options, os.Stdout, []*txtkv.ServiceInstance{instance})
+	} else if options.exists {
+		err = outputExists(options, os.Stdout, instance.Table.Has(key))
+	} else {
+		values, stepErr := getValues(options, instance.Table, key, defaultValues)
+		if stepErr != nil {
+			fail(options, stepErr.class, stepErr.err, name, key, "Error %s values for key \"%s\" in %s:\n%+v\n", stepErr.action, key, name, stepErr.err.Error())
+		}
+		err = output(options, os.Stdout, values)
+	}
+	if err != nil {
+		fail(options, classOutput, err, "", "", "Error writing output values: %s\n", err.Error())
+	}
+}
+
+// Plain and zero output have one line per SRV target: name, host, port, priority and weight, followed by the TXT
+// attributes (key=value, or just key for boolean attributes), all separated by tabs
+func outputInstances(options *options, sink io.Writer, instances []*txtkv.ServiceInstance) error {
+	if options.outputFormat == "json" {
+		type jsonTarget struct {
+			Host     string `json:"host"`
+			Port     uint16 `json:"port"`
+			Priority uint16 `json:"priority"`
+			Weight   uint16 `json:"weight"`
+		}
+		type jsonInstance struct {
+			Name     string              `json:"name"`
+			Instance string              `json:"instance"`
+			Targets  []jsonTarget        `json:"targets"`
+			Txt      map[string][]string `json:"txt"`
+		}
+		jsonInstances := []jsonInstance{}
+		for _, instance := range instances {
+			result := jsonInstance{
+				Name:     instance.Name,
+				Instance: instance.Instance,
+				Targets:  []jsonTarget{},
+				Txt:      instance.Table.Map(),
+			}
+			for _, target := range instance.Targets {
+				result.Targets = append(result.Targets, jsonTarget(target))
+			}
+			jsonInstances = append(jsonInstances, result)
+		}
+		encoder := json.NewEncoder(sink)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(jsonInstances); err != nil {
+			return errors.Wrap(err, "error writing JSON")
+		}
+		return nil
+	}
+
+	terminator := "\n"
+	if options.outputFormat == "zero" {
+		terminator = "\000"
+	}
+	for _, instance := range instances {
+		var attributes []string
+		for _, key := range instance.Table.Keys() {
+			if len(instance.Table.LookupList(key)) == 0 {
+				// Boolean attribute
+				attributes = append(attributes, key)
+			}
+			for _, value := range instance.Table.LookupList(key) {
+				attributes = append(attributes, key+"="+value)
+			}
+		}
+		for _, target := range instance.Targets {
+			fields := append([]string{
+				instance.Name,
+				target.Host,
+				fmt.Sprint(target.Port),
+				fmt.Sprint(target.Priority),
+				fmt.Sprint(target.Weight),
+			}, attributes...)
+			if _, err := fmt.Fprint(sink, strings.Join(fields, "\t"), terminator); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
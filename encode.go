@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/govau/sdget/txtkv"
+	"github.com/pkg/errors"
+)
+
+func runEncode(options *options, key string, value *string, maxLength int) {
+	plaintext, err := readValue(value)
+	if err != nil {
+		fail(options, classUsage, err, "", "", "Error reading value from standard input: %s\n", err.Error())
+	}
+
+	records, err := txtkv.ChunkRecords(key, plaintext, maxLength)
+	if err != nil {
+		fail(options, classUsage, err, "", key, "Error encoding value: %s\n", err.Error())
+	}
+
+	if err = outputRecords(options, os.Stdout, records); err != nil {
+		fail(options, classOutput, err, "", "", "Error writing TXT records: %s\n", err.Error())
+	}
+}
+
+// Values not given as arguments are read from standard input
+func readValue(value *string) (string, error) {
+	if value != nil {
+		return *value, nil
+	}
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Plain output is quoted like dig's output, one record per line, so it can be used as a file source or in a zone file
+func outputRecords(options *options, sink io.Writer, records []string) error {
+	switch options.outputFormat {
+	case "json":
+		encoder := json.NewEncoder(sink)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(records); err != nil {
+			return errors.Wrap(err, "error writing JSON")
+		}
+	case "plain":
+		for _, record := range records {
+			if _, err := fmt.Fprintln(sink, txtkv.QuoteTxt(record)); err != nil {
+				return err
+			}
+		}
+	case "zero":
+		for _, record := range records {
+			if _, err := fmt.Fprintf(sink, "%s\000", record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
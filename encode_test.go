@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+type outputRecordsTestPair struct {
+	Options *options
+	Records []string
+	Result  string
+}
+
+func TestOutputRecords(t *testing.T) {
+	for _, testPair := range []outputRecordsTestPair{
+		{defaultOptions, []string{"key=value"}, "\"key=value\"\n"},
+		{defaultOptions, []string{"cert#0=-----BEGIN\n", "cert#n=1"}, "\"cert#0=-----BEGIN\\010\"\n\"cert#n=1\"\n"},
+		{defaultOptions, []string{"name=café \"x\""}, "\"name=caf\\195\\169 \\\"x\\\"\"\n"},
+		{jsonListOptions, []string{"a=<b>", "c=\"d\""}, "[\"a=<b>\",\"c=\\\"d\\\"\"]\n"},
+		{zeroListOptions, []string{"a=1", "b=2"}, "a=1\000b=2\000"},
+	} {
+		var outBuffer bytes.Buffer
+		if err := outputRecords(testPair.Options, &outBuffer, testPair.Records); err != nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+		if result := outBuffer.String(); result != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
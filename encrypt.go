@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/govau/sdget/txtkv"
+	"github.com/pkg/errors"
+)
+
+func loadDecryptKeys(options *options, paths []string) error {
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, "error reading decryption key")
+		}
+		keys, err := txtkv.ParseDecryptionKeys(data)
+		if err != nil {
+			return errors.Wrapf(err, "error loading decryption keys from %s", path)
+		}
+		options.client.DecryptKeys = append(options.client.DecryptKeys, keys...)
+	}
+	return nil
+}
+
+// Encrypted values are decrypted, and are errors without decryption keys.  Other values are unchanged.
+func decryptValues(options *options, key string, values []string) ([]string, error) {
+	var result []string
+	for _, value := range values {
+		decrypted, err := txtkv.DecryptValue(options.client.DecryptKeys, key, value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, decrypted)
+	}
+	return result, nil
+}
+
+func runEncrypt(options *options, recipientPath string, key string, value *string, maxLength int) {
+	data, err := ioutil.ReadFile(recipientPath)
+	if err != nil {
+		fail(options, classConfig, err, "", "", "Error reading recipient key: %s\n", err.Error())
+	}
+	recipient, err := txtkv.ParseRecipientKey(data)
+	if err != nil {
+		fail(options, classConfig, err, "", "", "Error loading recipient key from %s: %s\n", recipientPath, err.Error())
+	}
+
+	plaintext, err := readValue(value)
+	if err != nil {
+		fail(options, classUsage, err, "", "", "Error reading value from standard input: %s\n", err.Error())
+	}
+	encrypted, err := txtkv.EncryptValue(recipient, key, plaintext)
+	if err != nil {
+		fail(options, classUsage, err, "", key, "Error encrypting value: %s\n", err.Error())
+	}
+	records, err := txtkv.ChunkRecords(key, encrypted, maxLength)
+	if err != nil {
+		fail(options, classUsage, err, "", key, "Error encoding value: %s\n", err.Error())
+	}
+
+	if err = outputRecords(options, os.Stdout, records); err != nil {
+		fail(options, classOutput, err, "", "", "Error writing TXT records: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/govau/sdget/txtkv"
+)
+
+func TestDecryptValues(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	encrypted, err := txtkv.EncryptValue(key.PublicKey(), "token", "s3cret")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+
+	values, err := decryptValues(defaultOptions, "token", []string{"plain"})
+	if err != nil || !reflect.DeepEqual(values, []string{"plain"}) {
+		t.Error("Expected plain values to be unchanged without keys but got", values, err)
+	}
+
+	if values, err := decryptValues(defaultOptions, "token", []string{encrypted}); !errors.Is(err, txtkv.ErrNoDecryptionKey) {
+		t.Error("Expected ErrNoDecryptionKey without keys but got", values, err)
+	}
+
+	options := &options{client: txtkv.Options{DecryptKeys: []*ecdh.PrivateKey{key}}}
+	values, err = decryptValues(options, "token", []string{encrypted, "plain"})
+	if err != nil || !reflect.DeepEqual(values, []string{"s3cret", "plain"}) {
+		t.Error("Expected [s3cret plain] but got", values, err)
+	}
+
+	if _, err := decryptValues(options, "other", []string{encrypted}); err == nil {
+		t.Error("Expected error for value moved to another key")
+	}
+}
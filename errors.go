@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/govau/sdget/txtkv"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Each class of error has a stable exit status, so that scripts can decide whether to retry, fall back or give up
+type errorClass struct {
+	name string
+	code int
+}
+
+var (
+	classUsage          = errorClass{"usage", 1}
+	classConfig         = errorClass{"config", 2}
+	classSource         = errorClass{"source", 3}
+	classKeyNotFound    = errorClass{"key-not-found", 4}
+	classOutput         = errorClass{"output", 5}
+	classInconsistent   = errorClass{"inconsistent", 6}
+	classInvalidValue   = errorClass{"invalid-value", 7}
+	classDecryption     = errorClass{"decryption", 8}
+	classSchema         = errorClass{"schema", 9}
+	classNetwork        = errorClass{"network", 10}
+	classNXDomain       = errorClass{"nxdomain", 11}
+	classServerFailure  = errorClass{"servfail", 12}
+	classFile           = errorClass{"file", 13}
+	classTooManyValues  = errorClass{"too-many-values", 14}
+	classAuthentication = errorClass{"authentication", 15}
+	classStale          = errorClass{"stale", 16}
+	classNoDecryptKey   = errorClass{"no-decryption-key", 17}
+)
+
+type errorReport struct {
+	Code   int    `json:"code"`
+	Class  string `json:"class"`
+	Source string `json:"source,omitempty"`
+	Key    string `json:"key,omitempty"`
+	Detail string `json:"detail"`
+	// Line is the input line number, for batch queries
+	Line int `json:"line,omitempty"`
+}
+
+// The class comes from the error itself where possible (like NXDOMAIN for a source named in an interpolated value),
+// otherwise from the step that failed
+func classifyError(err error, step errorClass) errorClass {
+	var sourceErr *txtkv.SourceError
+	var valueErr *txtkv.ValueError
+	var schemaErr *txtkv.SchemaError
+	switch {
+	case err == nil:
+		return step
+	case errors.Is(err, txtkv.ErrNetwork):
+		return classNetwork
+	case errors.Is(err, txtkv.ErrNoRecords):
+		return classNXDomain
+	case errors.Is(err, txtkv.ErrServerFailure):
+		return classServerFailure
+	case errors.Is(err, txtkv.ErrStaleSnapshot):
+		return classStale
+	case errors.Is(err, txtkv.ErrFileAccess):
+		return classFile
+	case errors.Is(err, txtkv.ErrBadSignature), errors.Is(err, txtkv.ErrNotAuthenticated), errors.Is(err, txtkv.ErrBadTSIG):
+		return classAuthentication
+	case errors.As(err, &sourceErr):
+		return classConfig
+	case errors.Is(err, txtkv.ErrKeyNotFound):
+		return classKeyNotFound
+	case errors.Is(err, txtkv.ErrTooManyValues):
+		return classTooManyValues
+	case errors.Is(err, txtkv.ErrNoDecryptionKey):
+		return classNoDecryptKey
+	case errors.Is(err, txtkv.ErrDecryption):
+		return classDecryption
+	case errors.As(err, &valueErr), errors.Is(err, txtkv.ErrInvalidChunks), errors.Is(err, txtkv.ErrInterpolationCycle), errors.Is(err, txtkv.ErrLocalReference):
+		return classInvalidValue
+	case errors.As(err, &schemaErr):
+		return classSchema
+	}
+	return step
+}
+
+func makeErrorReport(err error, step errorClass, source string, key string, detail string) errorReport {
+	class := classifyError(err, step)
+	var fetchErr *txtkv.FetchError
+	if errors.As(err, &fetchErr) {
+		source = fetchErr.Source
+	}
+	if err != nil {
+		detail = err.Error()
+	}
+	return errorReport{
+		Code:   class.code,
+		Class:  class.name,
+		Source: source,
+		Key:    key,
+		Detail: detail,
+	}
+}
+
+// fail reports an error on stderr and exits.  The plain message is printf-style, and is also the JSON detail if there's
+// no error value.  An empty message prints nothing in plain format (like when a report has already been output).
+func fail(options *options, step errorClass, err error, source string, key string, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	report := makeErrorReport(err, step, source, key, message)
+	if options.errorFormat == "json" {
+		encoder := json.NewEncoder(os.Stderr)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(report)
+	} else {
+		fmt.Fprint(os.Stderr, message)
+	}
+	os.Exit(report.Code)
+}
+
+// Like kingpin.Fatalf, but with JSON output for --error-format json
+func usageError(options *options, format string, args ...interface{}) {
+	if options.errorFormat != "json" {
+		kingpin.Fatalf(format, args...)
+	}
+	fail(options, classUsage, nil, "", "", format, args...)
+}
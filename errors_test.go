@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/govau/sdget/txtkv"
+)
+
+type makeErrorReportTestPair struct {
+	Err    error
+	Step   errorClass
+	Report errorReport
+}
+
+func TestMakeErrorReport(t *testing.T) {
+	fetchErr := &txtkv.FetchError{Source: "dns:shared.example.com", Err: fmt.Errorf("%w for domain shared.example.com.", txtkv.ErrNoRecords)}
+	for _, testPair := range []makeErrorReportTestPair{
+		{nil, classUsage, errorReport{1, "usage", "foo.example.com", "key", "message", 0}},
+		{errors.New("disk full"), classOutput, errorReport{5, "output", "foo.example.com", "key", "disk full", 0}},
+		{fetchErr, classSource, errorReport{11, "nxdomain", "dns:shared.example.com", "key", fetchErr.Error(), 0}},
+		{fmt.Errorf("error interpolating ${dns:shared.example.com/region}: %w", fetchErr), classKeyNotFound, errorReport{11, "nxdomain", "dns:shared.example.com", "key", "error interpolating ${dns:shared.example.com/region}: " + fetchErr.Error(), 0}},
+		{fmt.Errorf("%w executing DNS query: timeout", txtkv.ErrNetwork), classSource, errorReport{10, "network", "foo.example.com", "key", "network error executing DNS query: timeout", 0}},
+		{fmt.Errorf("%w: SERVFAIL", txtkv.ErrServerFailure), classSource, errorReport{12, "servfail", "foo.example.com", "key", "error from remote DNS server: SERVFAIL", 0}},
+		{fmt.Errorf("%w for TXT records: missing", txtkv.ErrFileAccess), classSource, errorReport{13, "file", "foo.example.com", "key", "error reading file for TXT records: missing", 0}},
+		{fmt.Errorf("%w: foo.snap was fetched 2h0m0s ago (max age 1h0m0s)", txtkv.ErrStaleSnapshot), classSource, errorReport{16, "stale", "foo.example.com", "key", "snapshot is too old: foo.snap was fetched 2h0m0s ago (max age 1h0m0s)", 0}},
+		{fmt.Errorf("error verifying foo.example.com: %w", txtkv.ErrBadSignature), classSource, errorReport{15, "authentication", "foo.example.com", "key", "error verifying foo.example.com: signature verification failed", 0}},
+		{fmt.Errorf("%w: response from 10.0.0.2:53 isn't signed", txtkv.ErrBadTSIG), classSource, errorReport{15, "authentication", "foo.example.com", "key", "TSIG verification failed: response from 10.0.0.2:53 isn't signed", 0}},
+		{&txtkv.SourceError{Source: "bogus:x", Err: txtkv.ErrUnsupportedScheme}, classKeyNotFound, errorReport{2, "config", "foo.example.com", "key", "invalid source \"bogus:x\": unsupported URI scheme", 0}},
+		{&txtkv.KeyError{Key: "key", Err: txtkv.ErrKeyNotFound}, classKeyNotFound, errorReport{4, "key-not-found", "foo.example.com", "key", "no values found for key key", 0}},
+		{&txtkv.KeyError{Key: "key", Count: 2, Err: txtkv.ErrTooManyValues}, classKeyNotFound, errorReport{14, "too-many-values", "foo.example.com", "key", "2 values found for key key, but only 1 was expected", 0}},
+		{fmt.Errorf("%w: value of key key is encrypted", txtkv.ErrNoDecryptionKey), classDecryption, errorReport{17, "no-decryption-key", "foo.example.com", "key", "no decryption key: value of key key is encrypted", 0}},
+		{fmt.Errorf("%w: bad", txtkv.ErrDecryption), classDecryption, errorReport{8, "decryption", "foo.example.com", "key", "decryption failed: bad", 0}},
+		{fmt.Errorf("%w: a -> a", txtkv.ErrInterpolationCycle), classKeyNotFound, errorReport{7, "invalid-value", "foo.example.com", "key", "interpolation cycle: a -> a", 0}},
+		{&txtkv.SchemaError{Problems: []string{"required key a is missing"}}, classSource, errorReport{9, "schema", "foo.example.com", "key", "schema violation: required key a is missing", 0}},
+	} {
+		report := makeErrorReport(testPair.Err, testPair.Step, "foo.example.com", "key", "message")
+
+		if report != testPair.Report {
+			t.Error("Expected", testPair.Report, "but got", report, "for", testPair)
+		}
+	}
+}
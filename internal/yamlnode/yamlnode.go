@@ -0,0 +1,36 @@
+// Package yamlnode has the helpers for walking yaml.v3 documents that config files and YAML record files share.
+package yamlnode
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ResolveAlias follows an alias (like *base) to the node with its anchor.  Other nodes are returned as they are.
+func ResolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// ForEachPair calls f with each key and (alias-resolved) value of a mapping, in file order.  Unlike decoding into a
+// map, the yaml.Node API doesn't catch duplicate keys, so that's done here.
+func ForEachPair(node *yaml.Node, f func(key string, value *yaml.Node) error) error {
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if key.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: keys must be strings", key.Line)
+		}
+		if seen[key.Value] {
+			return fmt.Errorf("line %d: duplicate key \"%s\"", key.Line, key.Value)
+		}
+		seen[key.Value] = true
+		if err := f(key.Value, ResolveAlias(node.Content[i+1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
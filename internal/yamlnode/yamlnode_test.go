@@ -0,0 +1,47 @@
+package yamlnode
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type forEachPairTestPair struct {
+	Document string
+	Keys     []string
+	Values   []string
+	IsErr    bool
+}
+
+func TestForEachPair(t *testing.T) {
+	for _, testPair := range []forEachPairTestPair{
+		{"a: 1\nb: 2\n", []string{"a", "b"}, []string{"1", "2"}, false},
+		{"a: &x 1\nb: *x\n", []string{"a", "b"}, []string{"1", "1"}, false},
+		{"a: 1\na: 2\n", nil, nil, true},
+		{"[a]: 1\n", nil, nil, true},
+	} {
+		var document yaml.Node
+		if err := yaml.Unmarshal([]byte(testPair.Document), &document); err != nil {
+			t.Fatal("Error", err.Error(), "parsing", testPair.Document)
+		}
+		var keys, values []string
+		err := ForEachPair(document.Content[0], func(key string, value *yaml.Node) error {
+			keys, values = append(keys, key), append(values, value.Value)
+			return nil
+		})
+		if testPair.IsErr {
+			if err == nil {
+				t.Error("Expected an error for", testPair.Document)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error("Error", err.Error(), "for", testPair.Document)
+			continue
+		}
+		if !reflect.DeepEqual(keys, testPair.Keys) || !reflect.DeepEqual(values, testPair.Values) {
+			t.Error("Expected", testPair.Keys, testPair.Values, "but got", keys, values, "for", testPair.Document)
+		}
+	}
+}
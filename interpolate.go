@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+
+	"github.com/govau/sdget/txtkv"
+)
+
+// With --interpolate, references like ${host} or ${dns:shared.example.com/region} in values (including defaults) are
+// expanded.  Other values are unchanged.
+func interpolateValues(options *options, table *txtkv.Table, key string, values []string) ([]string, error) {
+	if !options.interpolate {
+		return values, nil
+	}
+	client := txtkv.NewClient(options.client)
+	var result []string
+	for _, value := range values {
+		expanded, err := client.Interpolate(context.Background(), table, key, value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expanded)
+	}
+	return result, nil
+}
@@ -1,66 +1,48 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
+	"github.com/govau/sdget/txtkv"
 	"github.com/pkg/errors"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
-type txtProvider interface {
-	getTxtRecords() ([]string, error)
-}
-
-func getTxtProvider(options *options, source string) (txtProvider, error) {
-	if strings.ContainsRune(source, ':') {
-		uri, err := parseURI(source)
-		if err != nil {
-			return nil, err
-		}
-		switch uri.scheme {
-		case "dns":
-			domain := uri.path
-			if uri.query != "" {
-				return nil, fmt.Errorf("unexpected \"%s\": queries in DNS URIs not supported", uri.query)
-			}
-			if uri.fragment != "" {
-				return nil, fmt.Errorf("unexpected \"%s\": fragments in DNS URIs not supported", uri.fragment)
-			}
-			if strings.HasPrefix(domain, "/") {
-				domain = domain[1:len(domain)]
-			}
-			return makeDnsProvider(options, uri.authority, domain)
-
-		case "file":
-			if uri.query != "" {
-				return nil, fmt.Errorf("unexpected \"%s\": queries in file URIs not supported", uri.query)
-			}
-			if uri.fragment != "" {
-				return nil, fmt.Errorf("unexpected \"%s\": fragments in file URIs not supported", uri.fragment)
-			}
-			return makeFileProvider(options, uri.authority, uri.path)
-
-		default:
-			return nil, fmt.Errorf("Unsupported URI scheme: %s", uri.scheme)
-		}
-	}
-	return makeDnsProvider(options, "", source)
-}
-
 type options struct {
 	outputFormat string
 	valueType    string
-	nameserver   string
+	sources      []string
+	policy       string
+	showSource   bool
+	exists       bool
+	as           string
+	match        string
+	regex        string
+	prefix       string
+	stripPrefix  bool
+	nest         bool
+	interpolate  bool
+	schema       *txtkv.Schema
+	errorFormat  string
+	config       *appliedConfig
+	client       txtkv.Options
 }
 
 func makeDefaultOptions() *options {
 	return &options{
 		outputFormat: "plain",
+		errorFormat:  "plain",
 		valueType:    "single",
+		policy:       txtkv.LayerFirstWins,
+		client: txtkv.Options{
+			Resolve: txtkv.ResolveRecursive,
+		},
 	}
 }
 
@@ -70,14 +52,22 @@ func output(options *options, sink io.Writer, values []string) error {
 	}
 	switch options.outputFormat {
 	case "json":
+		nativeValues := []interface{}{}
+		for _, value := range values {
+			native, err := nativeValue(options.as, value)
+			if err != nil {
+				return err
+			}
+			nativeValues = append(nativeValues, native)
+		}
 		var err error
 		encoder := json.NewEncoder(sink)
 		encoder.SetEscapeHTML(false)
 		switch options.valueType {
 		case "single":
-			err = encoder.Encode(values[0])
+			err = encoder.Encode(nativeValues[0])
 		case "list":
-			err = encoder.Encode(values)
+			err = encoder.Encode(nativeValues)
 		}
 		if err != nil {
 			return errors.Wrap(err, "error writing JSON")
@@ -98,111 +88,422 @@ func output(options *options, sink io.Writer, values []string) error {
 	return nil
 }
 
-func splitRecord(record string) (isRecord bool, key string, value string) {
-	// Implements the rules in https://tools.ietf.org/html/rfc1464#page-2
-	// * Escaping done with `
-	// * Unescaped trailing and leading tabs and spaces removed
-	// * Key ends with unescaped =
-	record = strings.TrimLeft(record, " \t")
-	var bkey []byte
-	escapeNext := false
-	numTrailingWhitespace := 0
-	for i, c := range []byte(record) {
-		if escapeNext {
-			numTrailingWhitespace = 0
-			bkey = append(bkey, c)
-			escapeNext = false
-			continue
+// Sources are shown as an extra column before each value
+func outputWithSources(options *options, sink io.Writer, values []string, sources []string) error {
+	if options.valueType == "single" && len(values) != 1 {
+		return fmt.Errorf("expected 1 value but got %d (%v)", len(values), values)
+	}
+	switch options.outputFormat {
+	case "json":
+		type sourcedValue struct {
+			Value  interface{} `json:"value"`
+			Source string      `json:"source"`
 		}
-
-		if c == '`' {
-			escapeNext = true
-			continue
+		sourcedValues := []sourcedValue{}
+		for i, value := range values {
+			native, err := nativeValue(options.as, value)
+			if err != nil {
+				return err
+			}
+			sourcedValues = append(sourcedValues, sourcedValue{native, sources[i]})
 		}
-
-		if c == '=' {
-			bkey = bkey[0 : len(bkey)-numTrailingWhitespace]
-			key = strings.ToLower(string(bkey))
-			return true, key, record[i+1 : len(record)]
+		var err error
+		encoder := json.NewEncoder(sink)
+		encoder.SetEscapeHTML(false)
+		switch options.valueType {
+		case "single":
+			err = encoder.Encode(sourcedValues[0])
+		case "list":
+			err = encoder.Encode(sourcedValues)
 		}
-
-		if c == ' ' || c == '\t' {
-			numTrailingWhitespace++
-		} else {
-			numTrailingWhitespace = 0
+		if err != nil {
+			return errors.Wrap(err, "error writing JSON")
+		}
+	case "plain":
+		for i, record := range values {
+			if _, err := fmt.Fprintf(sink, "%s\t%s\n", sources[i], record); err != nil {
+				return err
+			}
+		}
+	case "zero":
+		for i, record := range values {
+			if _, err := fmt.Fprintf(sink, "%s\000%s\000", sources[i], record); err != nil {
+				return err
+			}
 		}
-		bkey = append(bkey, c)
 	}
-	return false, "", ""
+	return nil
 }
 
-func lookUpValues(options *options, txtRecords []string, key string, defaultValues []string) ([]string, error) {
+func lookUpValues(options *options, table *txtkv.Table, key string, defaultValues []string) ([]string, error) {
 	key = strings.ToLower(key)
-	var values []string
-	for _, record := range txtRecords {
-		isRecord, recordKey, recordValue := splitRecord(record)
-		if isRecord && recordKey == key {
-			values = append(values, recordValue)
-		}
+	values := table.LookupList(key)
+	chunked, isChunked, err := table.ReassembleChunks(key)
+	if err != nil {
+		return nil, err
 	}
-	if len(values) == 0 {
+	if isChunked {
+		values = []string{chunked}
+	}
+	if len(values) == 0 && !table.Has(key) {
 		values = defaultValues
 	}
 
 	if options.valueType == "single" {
+		if len(values) == 0 && table.Has(key) {
+			return nil, errors.Errorf("key %s is a boolean attribute with no value (try --exists)", key)
+		}
 		if len(values) == 0 {
-			return nil, errors.Errorf("no values found for key %s, and no default provided", key)
+			return nil, &txtkv.KeyError{Key: key, Err: txtkv.ErrKeyNotFound}
 		}
 		if len(values) > 1 {
-			return nil, errors.Errorf("%d values found for key %s, but only 1 was expected", len(values), key)
+			return nil, &txtkv.KeyError{Key: key, Count: len(values), Err: txtkv.ErrTooManyValues}
 		}
 	}
 
 	return values, nil
 }
 
-func main() {
-	options := makeDefaultOptions()
-	kingpin.Version("0.4.0")
-	kingpin.CommandLine.HelpFlag.Short('h')
-	kingpin.Flag("format", "Output format (json, plain, zero)").Short('f').Default("plain").Envar("SDGET_FORMAT").EnumVar(&options.outputFormat, "json", "plain", "zero")
-	kingpin.Flag("nameserver", "Default nameserver address (ns.example.com:53, 127.0.0.1)").Short('@').Envar("SDGET_NAMESERVER").StringVar(&options.nameserver)
-	kingpin.Flag("type", "Data value type (single, list)").Short('t').Default("single").Envar("SDGET_TYPE").EnumVar(&options.valueType, "single", "list")
-	source := kingpin.Arg("source", "URI or domain name to query for TXT records").Required().String()
-	key := kingpin.Arg("key", "Key name to look up in source").Required().String()
-	defaultValues := kingpin.Arg("default", "Default value(s) to use if key is not found").Strings()
-	kingpin.Parse()
+// A failed step of getValues, for error reporting
+type stepError struct {
+	class  errorClass
+	action string
+	err    error
+}
 
-	if *defaultValues == nil {
-		defaultValues = &[]string{}
+// getValues looks up the values of a key, then decrypts, interpolates and decodes them as the options say
+func getValues(options *options, table *txtkv.Table, key string, defaultValues []string) ([]string, *stepError) {
+	values, err := lookUpValues(options, table, key, defaultValues)
+	if err != nil {
+		return nil, &stepError{classKeyNotFound, "looking up", err}
+	}
+	values, err = decryptValues(options, key, values)
+	if err != nil {
+		return nil, &stepError{classDecryption, "decrypting", err}
+	}
+	values, err = interpolateValues(options, table, key, values)
+	if err != nil {
+		return nil, &stepError{classKeyNotFound, "interpolating", err}
 	}
+	if options.as != "" {
+		values, err = decodeValues(options.as, key, values)
+		if err != nil {
+			return nil, &stepError{classInvalidValue, "decoding", err}
+		}
+	}
+	return values, nil
+}
 
-	if options.valueType == "single" && len(*defaultValues) > 1 {
-		fmt.Fprintf(os.Stderr, "Got %d default values, but the value type is \"single\".  (Did you mean to set --type list?)\n", len(*defaultValues))
-		os.Exit(1)
+func outputExists(options *options, sink io.Writer, exists bool) error {
+	switch options.outputFormat {
+	case "json", "plain":
+		if _, err := fmt.Fprintln(sink, exists); err != nil {
+			return err
+		}
+	case "zero":
+		if _, err := fmt.Fprintf(sink, "%t\000", exists); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	provider, err := getTxtProvider(options, *source)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error setting up client: %s\n", err.Error())
-		os.Exit(2)
+func lookUpTable(options *options, sources []string) *txtkv.Table {
+	client := txtkv.NewClient(options.client)
+	for _, source := range sources {
+		if _, err := client.Provider(source); err != nil {
+			fail(options, classConfig, err, source, "", "Error setting up client: %s\n", err.Error())
+		}
 	}
 
-	txtRecords, err := provider.getTxtRecords()
+	table, err := client.LayeredTable(context.Background(), sources, options.policy)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error looking up TXT records:\n%+v\n", err.Error())
-		os.Exit(3)
+		fail(options, classSource, err, strings.Join(sources, ", "), "", "Error looking up TXT records:\n%+v\n", err.Error())
+	}
+	return table
+}
+
+func runGet(options *options, sources []string, key string, defaultValues []string) {
+	sourceList := strings.Join(sources, ", ")
+	if options.valueType == "single" && len(defaultValues) > 1 {
+		fail(options, classUsage, nil, sourceList, key, "Got %d default values, but the value type is \"single\".  (Did you mean to set --type list?)\n", len(defaultValues))
+	}
+
+	table := lookUpTable(options, sources)
+	checkSchema(options, table, sourceList)
+
+	if options.exists {
+		if err := outputExists(options, os.Stdout, table.Has(key)); err != nil {
+			fail(options, classOutput, err, "", "", "Error writing output values: %s\n", err.Error())
+		}
+		return
+	}
+
+	values, stepErr := getValues(options, table, key, defaultValues)
+	if stepErr != nil {
+		fail(options, stepErr.class, stepErr.err, sourceList, key, "Error %s values for key \"%s\" in %s:\n%+v\n", stepErr.action, key, sourceList, stepErr.err.Error())
 	}
 
-	var values []string
-	values, err = lookUpValues(options, txtRecords, *key, *defaultValues)
+	var err error
+	if options.showSource {
+		valueSources := table.LookupSources(key)
+		if len(valueSources) == 0 {
+			valueSources = table.LookupSources(txtkv.ChunkKey(key, 0))
+		}
+		if len(valueSources) == 0 {
+			valueSources = make([]string, len(values))
+			for i := range valueSources {
+				valueSources[i] = "default"
+			}
+		}
+		err = outputWithSources(options, os.Stdout, values, valueSources)
+	} else {
+		err = output(options, os.Stdout, values)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error looking up values for key \"%s\" in %s:\n%+v\n", *key, *source, err.Error())
-		os.Exit(4)
+		fail(options, classOutput, err, "", "", "Error writing output values: %s\n", err.Error())
+	}
+}
+
+func main() {
+	options := makeDefaultOptions()
+	kingpin.Version("0.4.0")
+	kingpin.CommandLine.HelpFlag.Short('h')
+	kingpin.Flag("format", "Output format (json, plain, zero)").Short('f').Default("plain").Envar("SDGET_FORMAT").EnumVar(&options.outputFormat, "json", "plain", "zero")
+	kingpin.Flag("error-format", "Error output format on stderr (plain, json)").Default("plain").Envar("SDGET_ERROR_FORMAT").EnumVar(&options.errorFormat, "plain", "json")
+	trace := kingpin.Flag("trace", "Log each step of lookups (source parsing, DNS queries and responses, record parsing) to stderr").Short('v').Envar("SDGET_TRACE").Bool()
+	kingpin.Flag("nameserver", "Default nameserver address (ns.example.com:53, 127.0.0.1)").Short('@').Envar("SDGET_NAMESERVER").StringVar(&options.client.Nameserver)
+	kingpin.Flag("config", "Config file with defaults, profiles and source aliases (default /etc/sdget/config.yaml, then ~/.config/sdget/config.yaml)").PlaceHolder("CONFIG.YAML").Envar("SDGET_CONFIG").String()
+	kingpin.Flag("profile", "Named profile from the config file").Envar("SDGET_PROFILE").String()
+	kingpin.Flag("dnssec", "Require DNS responses to be authenticated by a DNSSEC-validating resolver").Envar("SDGET_DNSSEC").BoolVar(&options.client.DNSSEC)
+	tsigKey := kingpin.Flag("tsig-key", "Sign queries to the nameserver with a TSIG key (like dig -y), and require signed responses").PlaceHolder("[ALGORITHM:]NAME:SECRET").Envar("SDGET_TSIG_KEY").String()
+	kingpin.Flag("base-domain", "Domain that DNS names without a trailing dot are relative to (app means app.BASE-DOMAIN)").PlaceHolder("BASE-DOMAIN").Envar("SDGET_BASE_DOMAIN").StringVar(&options.client.BaseDomain)
+	kingpin.Flag("resolve", "DNS resolution method (recursive, iterative)").Default("recursive").Envar("SDGET_RESOLVE").EnumVar(&options.client.Resolve, txtkv.ResolveRecursive, txtkv.ResolveIterative)
+	kingpin.Flag("root-hints", "Root hints file for iterative resolution (named.root format)").Envar("SDGET_ROOT_HINTS").ExistingFileVar(&options.client.RootHints)
+	kingpin.Flag("max-age", "Maximum age of snapshot sources that don't set max-age themselves (like 24h)").PlaceHolder("DURATION").Envar("SDGET_MAX_AGE").DurationVar(&options.client.MaxSnapshotAge)
+	kingpin.Flag("mdns-window", "How long to collect multicast DNS responses for").Default("1s").Envar("SDGET_MDNS_WINDOW").DurationVar(&options.client.MulticastWindow)
+	kingpin.Flag("source", "Source to query, in order of precedence (repeatable, replaces the <source> argument)").Short('s').PlaceHolder("SOURCE").StringsVar(&options.sources)
+	kingpin.Flag("policy", "Policy for combining multiple sources (first-wins, merge, require-all)").Default(txtkv.LayerFirstWins).Envar("SDGET_POLICY").EnumVar(&options.policy, txtkv.LayerFirstWins, txtkv.LayerMerge, txtkv.LayerRequireAll)
+	kingpin.Flag("show-source", "Show the source of each value").Envar("SDGET_SHOW_SOURCE").BoolVar(&options.showSource)
+	kingpin.Flag("includes", "Follow "+txtkv.IncludeKey+" directives in TXT records").Envar("SDGET_INCLUDES").BoolVar(&options.client.Includes)
+	kingpin.Flag("max-include-depth", "Maximum nesting of include directives").Default("8").Envar("SDGET_MAX_INCLUDE_DEPTH").IntVar(&options.client.MaxIncludeDepth)
+	kingpin.Flag("max-fetches", "Maximum number of sources to fetch at the same time (layered sources, includes, batch queries)").Default("8").Envar("SDGET_MAX_FETCHES").IntVar(&options.client.MaxConcurrentFetches)
+	kingpin.Flag("syntax", "TXT record syntax (rfc1464, rfc6763; default rfc6763 for browse and resolve, rfc1464 otherwise)").Envar("SDGET_SYNTAX").EnumVar(&options.client.Syntax, txtkv.SyntaxRFC1464, txtkv.SyntaxRFC6763)
+	kingpin.Flag("exists", "Output whether the key is present (including boolean attributes) instead of its value(s)").Envar("SDGET_EXISTS").BoolVar(&options.exists)
+	kingpin.Flag("as", "Validate and normalise values as a type (int, float, bool, duration, base64, hex, json, url)").PlaceHolder("TYPE").Envar("SDGET_AS").EnumVar(&options.as, decodeTypes...)
+	verifyKeyPaths := kingpin.Flag("verify-key", "Only accept sources signed by a trusted Ed25519 public key in this PEM file (repeatable)").PlaceHolder("PUBKEY.PEM").Envar("SDGET_VERIFY_KEY").ExistingFiles()
+	decryptKeyPaths := kingpin.Flag("decrypt-key", "Decrypt "+txtkv.EncryptedPrefix+" values with an X25519 private key in this PEM file (repeatable)").PlaceHolder("KEY.PEM").Envar("SDGET_DECRYPT_KEY").ExistingFiles()
+	kingpin.Flag("match", "Output all keys matching a glob pattern (db.*) as a map, instead of looking up one key").PlaceHolder("PATTERN").StringVar(&options.match)
+	kingpin.Flag("regex", "Output all keys matching a regular expression as a map, instead of looking up one key").PlaceHolder("REGEX").StringVar(&options.regex)
+	kingpin.Flag("prefix", "Output all keys starting with a prefix (db.) as a map, instead of looking up one key").PlaceHolder("PREFIX").StringVar(&options.prefix)
+	kingpin.Flag("strip-prefix", "Remove the --prefix from keys in the output").Default("true").BoolVar(&options.stripPrefix)
+	kingpin.Flag("nest", "Output dotted keys (db.host) as nested JSON objects").BoolVar(&options.nest)
+	kingpin.Flag("interpolate", "Expand ${key} and ${source/key} references in values ($$ for a literal $)").Envar("SDGET_INTERPOLATE").BoolVar(&options.interpolate)
+	schemaPath := kingpin.Flag("schema", "Check the whole record set against a JSON schema file before output").PlaceHolder("SCHEMA.JSON").Envar("SDGET_SCHEMA").ExistingFile()
+	kingpin.Flag("type", "Data value type (single, list)").Short('t').Default("single").Envar("SDGET_TYPE").EnumVar(&options.valueType, "single", "list")
+
+	get := kingpin.Command("get", "Look up the value(s) of a key in a TXT record source").Default()
+	source := get.Arg("source", "URI or domain name to query for TXT records (omitted when using --source)").String()
+	key := get.Arg("key", "Key name to look up in source").String()
+	defaultValues := get.Arg("default", "Default value(s) to use if key is not found").Strings()
+
+	checkConsistencyCommand := kingpin.Command("check-consistency", "Compare the TXT records of a domain across all its authoritative nameservers")
+	checkDomain := checkConsistencyCommand.Arg("domain", "Domain name to check").Required().String()
+
+	browseCommand := kingpin.Command("browse", "List the instances of a DNS-SD service type, with their SRV and TXT records")
+	browseService := browseCommand.Arg("service", "Service type domain name (_http._tcp.example.com)").Required().String()
+
+	resolveCommand := kingpin.Command("resolve", "Look up a DNS-SD service instance, or the value(s) of a key in its TXT record")
+	resolveInstance := resolveCommand.Arg("instance", "Service instance domain name (Printer._ipp._tcp.example.com)").Required().String()
+	resolveKey := resolveCommand.Arg("key", "Key name to look up (omit to show the whole instance)").String()
+	resolveDefaultValues := resolveCommand.Arg("default", "Default value(s) to use if key is not found").Strings()
+
+	encryptCommand := kingpin.Command("encrypt", "Make TXT records for a key and a value encrypted to a recipient's public key")
+	encryptRecipient := encryptCommand.Flag("recipient", "Recipient's X25519 public key PEM file").Required().PlaceHolder("PUBKEY.PEM").ExistingFile()
+	encryptMaxLength := encryptCommand.Flag("max-length", "Maximum length of each TXT string").Default(strconv.Itoa(txtkv.MaxRecordLength)).Int()
+	encryptKey := encryptCommand.Arg("key", "Key name").Required().String()
+	encryptValueGiven := false
+	encryptValue := encryptCommand.Arg("value", "Value (read from standard input if omitted)").Action(func(*kingpin.ParseContext) error {
+		encryptValueGiven = true
+		return nil
+	}).String()
+
+	signCommand := kingpin.Command("sign", "Make signature records for the TXT records of a source")
+	signKeyPaths := signCommand.Flag("key", "Ed25519 private key PEM file to sign with (repeatable, for key rotation)").Required().PlaceHolder("KEY.PEM").ExistingFiles()
+	signName := signCommand.Flag("name", "DNS name the records will be published under (default the domain of the source)").PlaceHolder("DOMAIN").String()
+	signValidFor := signCommand.Flag("valid-for", "How long the signatures are valid for").Default("720h").Duration()
+	signSource := signCommand.Arg("source", "URI or domain name of the records to sign").Required().String()
+
+	encodeCommand := kingpin.Command("encode", "Make TXT records for a key and value, splitting big values into chunks")
+	encodeMaxLength := encodeCommand.Flag("max-length", "Maximum length of each TXT string").Default(strconv.Itoa(txtkv.MaxRecordLength)).Int()
+	encodeKey := encodeCommand.Arg("key", "Key name").Required().String()
+	encodeValueGiven := false
+	encodeValue := encodeCommand.Arg("value", "Value (read from standard input if omitted)").Action(func(*kingpin.ParseContext) error {
+		encodeValueGiven = true
+		return nil
+	}).String()
+
+	lintCommand := kingpin.Command("lint", "Check TXT record sources for invalid chunked values, and against --schema")
+	lintSources := lintCommand.Arg("source", "URIs or domain names to check, in order of precedence (or use --source)").Strings()
+
+	batchCommand := kingpin.Command("batch", "Look up many keys, reading one query per line from standard input")
+	batchConcurrency := batchCommand.Flag("concurrency", "Number of queries to run at once").Default("8").Int()
+
+	snapshotCommand := kingpin.Command("snapshot", "Save the TXT records of a source with metadata (nameserver, fetch time, TTL, DNSSEC status, SOA serial) for snapshot: sources")
+	snapshotOutput := snapshotCommand.Flag("output", "File to write the snapshot to (default standard output)").Short('o').PlaceHolder("FILE").String()
+	snapshotSource := snapshotCommand.Arg("source", "URI or domain name to take a snapshot of").Required().String()
+
+	dumpCommand := kingpin.Command("dump", "Print the TXT strings of a source, in a format that file sources can read (quoted lines, or a JSON array)")
+	dumpSource := dumpCommand.Arg("source", "URI or domain name to dump the records of").Required().String()
+
+	configCommand := kingpin.Command("config", "Show the configuration from config files, profiles, environment variables and flags")
+	configShowCommand := configCommand.Command("show", "Print the effective value of every flag and where it came from, and the source aliases")
+
+	args, config, configErr := applyConfig(kingpin.CommandLine, os.Args[1:])
+	if configErr != nil {
+		// Flags haven't been parsed, and might not parse without the config
+		if prescanFlag(os.Args[1:], "error-format", "SDGET_ERROR_FORMAT") == "json" {
+			options.errorFormat = "json"
+		}
+		fail(options, classConfig, configErr, "", "", "Error loading configuration: %s\n", configErr.Error())
+	}
+	options.config = config
+	command := kingpin.MustParse(kingpin.CommandLine.Parse(args))
+	if *trace {
+		options.client.Trace = os.Stderr
+	}
+	if (options.match != "" && options.regex != "") || (options.prefix != "" && (options.match != "" || options.regex != "")) {
+		usageError(options, "only one of --match, --regex and --prefix can be used, try --help")
+	}
+	if options.nest && options.outputFormat != "json" {
+		usageError(options, "--nest needs --format json, try --help")
 	}
+	if options.client.DNSSEC && options.client.Resolve == txtkv.ResolveIterative {
+		usageError(options, "--dnssec needs a validating recursive resolver, so it can't be used with --resolve iterative, try --help")
+	}
+	if *tsigKey != "" && options.client.Resolve == txtkv.ResolveIterative {
+		usageError(options, "--tsig-key is shared with one nameserver, so it can't be used with --resolve iterative, try --help")
+	}
+	if options.client.MaxConcurrentFetches < 1 {
+		usageError(options, "--max-fetches must be at least 1, try --help")
+	}
+	if *tsigKey != "" {
+		key, err := txtkv.ParseTSIGKey(*tsigKey)
+		if err != nil {
+			fail(options, classConfig, err, "", "", "Error setting up client: %s\n", err.Error())
+		}
+		options.client.TSIGKey = key
+	}
+	if err := loadVerifyKeys(options, *verifyKeyPaths); err != nil {
+		fail(options, classConfig, err, "", "", "Error setting up client: %s\n", err.Error())
+	}
+	if err := loadDecryptKeys(options, *decryptKeyPaths); err != nil {
+		fail(options, classConfig, err, "", "", "Error setting up client: %s\n", err.Error())
+	}
+	if err := loadSchema(options, *schemaPath); err != nil {
+		fail(options, classConfig, err, "", "", "Error setting up client: %s\n", err.Error())
+	}
+
+	switch command {
+	case get.FullCommand():
+		sources := options.sources
+		baseDomain := options.client.BaseDomain
+		if len(sources) > 0 {
+			// The positional arguments all move along one place
+			if *key != "" {
+				*defaultValues = append([]string{*key}, *defaultValues...)
+			}
+			*key = *source
+		} else if baseDomain != "" && !options.matchesKeys() && *source != "" && *key == "" {
+			// With only a key, the source is the base domain itself
+			*key = *source
+			sources = []string{strings.TrimSuffix(baseDomain, ".") + "."}
+		} else if baseDomain != "" && options.matchesKeys() && *source == "" {
+			sources = []string{strings.TrimSuffix(baseDomain, ".") + "."}
+		} else {
+			sources = []string{*source}
+		}
+		sources = resolveAliases(options, sources)
+		if options.matchesKeys() {
+			if *key != "" || len(*defaultValues) > 0 {
+				usageError(options, "keys and default values can't be used with --match, --regex or --prefix, try --help")
+			}
+			if sources[0] == "" {
+				usageError(options, "required argument 'source' not provided, try --help")
+			}
+			runGetMap(options, sources)
+			break
+		}
+		if *key == "" {
+			usageError(options, "required argument(s) 'source' and 'key' not provided, try --help")
+		}
+		if *defaultValues == nil {
+			defaultValues = &[]string{}
+		}
+		if options.exists && len(*defaultValues) > 0 {
+			usageError(options, "default values can't be used with --exists, try --help")
+		}
+		runGet(options, sources, *key, *defaultValues)
+
+	case checkConsistencyCommand.FullCommand():
+		runCheckConsistency(options, *checkDomain)
+
+	case browseCommand.FullCommand():
+		runBrowse(options, *browseService)
+
+	case resolveCommand.FullCommand():
+		if options.exists && (*resolveKey == "" || len(*resolveDefaultValues) > 0) {
+			usageError(options, "--exists needs a key and no default values, try --help")
+		}
+		runResolve(options, *resolveInstance, *resolveKey, *resolveDefaultValues)
+
+	case encodeCommand.FullCommand():
+		if !encodeValueGiven {
+			encodeValue = nil
+		}
+		runEncode(options, *encodeKey, encodeValue, *encodeMaxLength)
+
+	case signCommand.FullCommand():
+		if *signValidFor <= 0 {
+			usageError(options, "--valid-for must be positive, try --help")
+		}
+		runSign(options, resolveAliases(options, []string{*signSource})[0], *signKeyPaths, *signName, *signValidFor)
+
+	case encryptCommand.FullCommand():
+		if !encryptValueGiven {
+			encryptValue = nil
+		}
+		runEncrypt(options, *encryptRecipient, *encryptKey, encryptValue, *encryptMaxLength)
+
+	case batchCommand.FullCommand():
+		if options.exists || options.matchesKeys() || options.showSource {
+			usageError(options, "--exists, --show-source, --match, --regex and --prefix can't be used with batch, try --help")
+		}
+		if options.outputFormat == "zero" {
+			usageError(options, "batch output can only be json or plain, try --help")
+		}
+		if *batchConcurrency < 1 {
+			usageError(options, "--concurrency must be at least 1, try --help")
+		}
+		runBatch(options, os.Stdin, *batchConcurrency)
+
+	case lintCommand.FullCommand():
+		if len(options.sources) > 0 && len(*lintSources) > 0 {
+			usageError(options, "sources can be arguments or given with --source, but not both, try --help")
+		}
+		sources := append(options.sources, *lintSources...)
+		if len(sources) == 0 {
+			usageError(options, "required argument 'source' not provided, try --help")
+		}
+		runLint(options, resolveAliases(options, sources))
+
+	case snapshotCommand.FullCommand():
+		runSnapshot(options, resolveAliases(options, []string{*snapshotSource})[0], *snapshotOutput)
+
+	case dumpCommand.FullCommand():
+		runDump(options, resolveAliases(options, []string{*dumpSource})[0])
 
-	if err = output(options, os.Stdout, values); err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing output values: %s\n", err.Error())
-		os.Exit(5)
+	case configShowCommand.FullCommand():
+		runConfigShow(options, args, command)
 	}
 }
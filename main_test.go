@@ -6,6 +6,9 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+
+	"github.com/govau/sdget/txtkv"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 var sampleTxtRecords = []string{
@@ -44,6 +47,24 @@ var zeroListOptions = &options{
 	outputFormat: "zero",
 	valueType:    "list",
 }
+var rfc6763Options = &options{
+	outputFormat: "plain",
+	valueType:    "single",
+	client:       txtkv.Options{Syntax: txtkv.SyntaxRFC6763},
+}
+var rfc6763ListOptions = &options{
+	outputFormat: "plain",
+	valueType:    "list",
+	client:       txtkv.Options{Syntax: txtkv.SyntaxRFC6763},
+}
+
+var sampleDnssdRecords = []string{
+	"txtvers=1",
+	"PaperTray",
+	"empty=",
+	"TXTVERS=2",
+	"`escaped`=value",
+}
 
 type outputTestPair struct {
 	Options *options
@@ -90,6 +111,33 @@ func TestOutput(t *testing.T) {
 	}
 }
 
+func TestOutputWithSources(t *testing.T) {
+	for _, testPair := range []outputTestPair{
+		{plainListOptions, []string{"foo", "bar"}, "a\tfoo\nb\tbar\n", nil},
+		{jsonSingleOptions, []string{"foo"}, "{\"value\":\"foo\",\"source\":\"a\"}\n", nil},
+		{jsonListOptions, []string{"foo", "bar"}, "[{\"value\":\"foo\",\"source\":\"a\"},{\"value\":\"bar\",\"source\":\"b\"}]\n", nil},
+		{jsonListOptions, []string{}, "[]\n", nil},
+		{zeroListOptions, []string{"foo", "bar"}, "a\000foo\000b\000bar\000", nil},
+		{defaultOptions, []string{"foo", "bar"}, "", errors.New("Too many values")},
+	} {
+		var outBuffer bytes.Buffer
+		err := outputWithSources(testPair.Options, &outBuffer, testPair.Values, []string{"a", "b"})
+		result := outBuffer.String()
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if result != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
+
 type lookUpValuesTestPair struct {
 	Options       *options
 	TxtRecords    []string
@@ -120,8 +168,22 @@ func TestLookUpValues(t *testing.T) {
 		{plainListOptions, sampleTxtRecords, "foo", []string{}, []string{"bar"}, nil},
 		{plainListOptions, sampleTxtRecords, "nosuchkey", []string{}, []string{}, nil},
 		{plainListOptions, sampleTxtRecords, "nosuchkey", []string{"1", "2"}, []string{"1", "2"}, nil},
+		{rfc6763Options, sampleDnssdRecords, "txtvers", []string{}, []string{"1"}, nil},
+		{rfc6763Options, sampleDnssdRecords, "empty", []string{"default"}, []string{""}, nil},
+		{rfc6763Options, sampleDnssdRecords, "papertray", []string{"default"}, nil, errors.New("Boolean attribute")},
+		{rfc6763Options, sampleDnssdRecords, "`escaped`", []string{}, []string{"value"}, nil},
+		{rfc6763Options, sampleDnssdRecords, "nosuchkey", []string{"default"}, []string{"default"}, nil},
+		{rfc6763ListOptions, sampleDnssdRecords, "papertray", []string{"default"}, []string{}, nil},
+		{rfc6763ListOptions, sampleDnssdRecords, "txtvers", []string{}, []string{"1"}, nil},
+		{defaultOptions, []string{"cert#1=world", "cert#0=hello ", "cert#n=2"}, "cert", []string{}, []string{"hello world"}, nil},
+		{defaultOptions, []string{"cert#1=world", "cert#n=1"}, "cert", []string{"default"}, nil, errors.New("Missing chunk")},
+		{plainListOptions, []string{"cert#0=a", "cert#1=b", "cert#n=2"}, "cert", []string{}, []string{"ab"}, nil},
 	} {
-		result, err := lookUpValues(testPair.Options, testPair.TxtRecords, testPair.Key, testPair.DefaultValues)
+		table := txtkv.ParseRecords(testPair.TxtRecords)
+		if testPair.Options.client.Syntax == txtkv.SyntaxRFC6763 {
+			table = txtkv.ParseAttributes(testPair.TxtRecords)
+		}
+		result, err := lookUpValues(testPair.Options, table, testPair.Key, testPair.DefaultValues)
 
 		if err != nil && testPair.Err == nil {
 			t.Error("Unexpected error", err.Error(), "for", testPair)
@@ -138,3 +200,61 @@ func TestLookUpValues(t *testing.T) {
 		}
 	}
 }
+
+type outputExistsTestPair struct {
+	Options *options
+	Exists  bool
+	Result  string
+}
+
+func TestOutputExists(t *testing.T) {
+	for _, testPair := range []outputExistsTestPair{
+		{defaultOptions, true, "true\n"},
+		{defaultOptions, false, "false\n"},
+		{jsonSingleOptions, true, "true\n"},
+		{zeroListOptions, false, "false\000"},
+	} {
+		var outBuffer bytes.Buffer
+		if err := outputExists(testPair.Options, &outBuffer, testPair.Exists); err != nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+		if result := outBuffer.String(); result != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
+
+type implicitGetTestPair struct {
+	Args    []string
+	Command string
+	Source  string
+}
+
+// get is the default command, and the first argument is only another command if it's exactly that command's name, so
+// domain names that start with a command name don't need an explicit get
+func TestImplicitGet(t *testing.T) {
+	for _, testPair := range []implicitGetTestPair{
+		{[]string{"list.example.com", "key"}, "get", "list.example.com"},
+		{[]string{"config.example.com", "key"}, "get", "config.example.com"},
+		{[]string{"batch.", "key"}, "get", "batch."},
+		{[]string{"get", "batch", "key"}, "get", "batch"},
+		{[]string{"batch"}, "batch", ""},
+		{[]string{"config", "show"}, "config show", ""},
+	} {
+		app := kingpin.New("sdget", "")
+		get := app.Command("get", "").Default()
+		source := get.Arg("source", "").String()
+		get.Arg("key", "").String()
+		app.Command("batch", "")
+		app.Command("config", "").Command("show", "")
+
+		command, err := app.Parse(testPair.Args)
+		if err != nil {
+			t.Error("Error", err.Error(), "for", testPair.Args)
+			continue
+		}
+		if command != testPair.Command || *source != testPair.Source {
+			t.Error("Expected", testPair.Command, testPair.Source, "but got", command, *source, "for", testPair.Args)
+		}
+	}
+}
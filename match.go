@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/govau/sdget/txtkv"
+	"github.com/pkg/errors"
+)
+
+// A key selected with --match, --regex or --prefix.  Boolean attributes (with --syntax rfc6763) have no values.
+type mapEntry struct {
+	key     string
+	name    string
+	values  []string
+	boolean bool
+}
+
+func (o *options) matchesKeys() bool {
+	return o.match != "" || o.regex != "" || o.prefix != ""
+}
+
+func makeKeyMatcher(options *options) (func(key string) bool, error) {
+	switch {
+	case options.match != "":
+		pattern := strings.ToLower(options.match)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid --match pattern \"%s\"", options.match)
+		}
+		return func(key string) bool {
+			matched, _ := path.Match(pattern, key)
+			return matched
+		}, nil
+
+	case options.regex != "":
+		re, err := regexp.Compile("(?i)" + options.regex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid --regex pattern \"%s\"", options.regex)
+		}
+		return re.MatchString, nil
+
+	default:
+		prefix := strings.ToLower(options.prefix)
+		return func(key string) bool {
+			return strings.HasPrefix(key, prefix)
+		}, nil
+	}
+}
+
+func lookUpMap(options *options, table *txtkv.Table) ([]mapEntry, error) {
+	matcher, err := makeKeyMatcher(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []mapEntry
+	for _, key := range table.LogicalKeys() {
+		if !matcher(key) {
+			continue
+		}
+		name := key
+		if options.prefix != "" && options.stripPrefix {
+			name = key[len(strings.ToLower(options.prefix)):]
+		}
+		if _, isChunked, _ := table.ReassembleChunks(key); !isChunked && table.Has(key) && len(table.LookupList(key)) == 0 {
+			entries = append(entries, mapEntry{key: key, name: name, boolean: true})
+			continue
+		}
+		values, err := lookUpValues(options, table, key, nil)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, mapEntry{key: key, name: name, values: values})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries, nil
+}
+
+// Plain and zero output have the key and value of each entry, separated by a tab or zero byte.  Keys with more than
+// one value (with --type list) are repeated.
+func outputMap(options *options, sink io.Writer, entries []mapEntry) error {
+	if options.outputFormat == "json" {
+		result := make(map[string]interface{})
+		for _, entry := range entries {
+			var value interface{}
+			if !entry.boolean {
+				natives := []interface{}{}
+				for _, value := range entry.values {
+					native, err := nativeValue(options.as, value)
+					if err != nil {
+						return err
+					}
+					natives = append(natives, native)
+				}
+				value = natives
+				if options.valueType == "single" {
+					value = natives[0]
+				}
+			}
+			if !options.nest {
+				result[entry.name] = value
+				continue
+			}
+			if err := setNested(result, strings.Split(entry.name, "."), value); err != nil {
+				return err
+			}
+		}
+		encoder := json.NewEncoder(sink)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(result); err != nil {
+			return errors.Wrap(err, "error writing JSON")
+		}
+		return nil
+	}
+
+	separator, terminator := "\t", "\n"
+	if options.outputFormat == "zero" {
+		separator, terminator = "\000", "\000"
+	}
+	for _, entry := range entries {
+		if entry.boolean {
+			if _, err := fmt.Fprint(sink, entry.name, terminator); err != nil {
+				return err
+			}
+		}
+		for _, value := range entry.values {
+			if _, err := fmt.Fprint(sink, entry.name, separator, value, terminator); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Dotted keys become nested objects, so db.host and db.port become {"db":{"host":...,"port":...}}
+func setNested(result map[string]interface{}, path []string, value interface{}) error {
+	for i, name := range path[:len(path)-1] {
+		existing, ok := result[name]
+		if !ok {
+			existing = make(map[string]interface{})
+			result[name] = existing
+		}
+		section, ok := existing.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("key %s has a value, so it can't also be a section", strings.Join(path[:i+1], "."))
+		}
+		result = section
+	}
+	name := path[len(path)-1]
+	if _, ok := result[name]; ok {
+		return errors.Errorf("key %s is a section, so it can't also have a value", strings.Join(path, "."))
+	}
+	result[name] = value
+	return nil
+}
+
+func runGetMap(options *options, sources []string) {
+	sourceList := strings.Join(sources, ", ")
+	table := lookUpTable(options, sources)
+	checkSchema(options, table, sourceList)
+
+	entries, err := lookUpMap(options, table)
+	if err != nil {
+		fail(options, classKeyNotFound, err, sourceList, "", "Error looking up matching keys in %s:\n%+v\n", sourceList, err.Error())
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		entry.values, err = decryptValues(options, entry.key, entry.values)
+		if err != nil {
+			fail(options, classDecryption, err, sourceList, entry.key, "Error decrypting values for key \"%s\" in %s:\n%+v\n", entry.key, sourceList, err.Error())
+		}
+		entry.values, err = interpolateValues(options, table, entry.key, entry.values)
+		if err != nil {
+			fail(options, classKeyNotFound, err, sourceList, entry.key, "Error interpolating values for key \"%s\" in %s:\n%+v\n", entry.key, sourceList, err.Error())
+		}
+		if options.as != "" && !entry.boolean {
+			entry.values, err = decodeValues(options.as, entry.key, entry.values)
+			if err != nil {
+				fail(options, classInvalidValue, err, sourceList, entry.key, "Error decoding values for key \"%s\" in %s:\n%+v\n", entry.key, sourceList, err.Error())
+			}
+		}
+	}
+
+	if err = outputMap(options, os.Stdout, entries); err != nil {
+		fail(options, classOutput, err, "", "", "Error writing output values: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/govau/sdget/txtkv"
+)
+
+var sampleSectionRecords = []string{
+	"db.host=db.example.com",
+	"DB.Port=5432",
+	"db.replica.host=replica.example.com",
+	"db.cert#0=abc",
+	"db.cert#1=def",
+	"db.cert#n=2",
+	"dbx=1",
+	"name=service",
+	"sdget-include=file:/tmp/shared",
+}
+
+type outputMapTestPair struct {
+	Options *options
+	Records []string
+	Result  string
+	Err     error
+}
+
+func TestOutputMap(t *testing.T) {
+	for _, testPair := range []outputMapTestPair{
+		{&options{outputFormat: "plain", valueType: "single", prefix: "db.", stripPrefix: true}, sampleSectionRecords,
+			"cert\tabcdef\nhost\tdb.example.com\nport\t5432\nreplica.host\treplica.example.com\n", nil},
+		{&options{outputFormat: "json", valueType: "single", prefix: "DB.", stripPrefix: false}, sampleSectionRecords,
+			`{"db.cert":"abcdef","db.host":"db.example.com","db.port":"5432","db.replica.host":"replica.example.com"}` + "\n", nil},
+		{&options{outputFormat: "json", valueType: "single", prefix: "db.", stripPrefix: true, nest: true}, sampleSectionRecords,
+			`{"cert":"abcdef","host":"db.example.com","port":"5432","replica":{"host":"replica.example.com"}}` + "\n", nil},
+		{&options{outputFormat: "json", valueType: "single", match: "*", nest: true}, []string{"a=1", "a.b=2"}, "", errors.New("Value and section")},
+		{&options{outputFormat: "json", valueType: "list", match: "db*.host"}, sampleSectionRecords,
+			`{"db.host":["db.example.com"],"db.replica.host":["replica.example.com"]}` + "\n", nil},
+		{&options{outputFormat: "zero", valueType: "single", regex: "^DB[^.]"}, sampleSectionRecords, "dbx\0001\000", nil},
+		{&options{outputFormat: "json", valueType: "single", match: "*"}, []string{"list=1", "list=2"}, "", errors.New("Too many values")},
+		{&options{outputFormat: "json", valueType: "single", match: "["}, sampleSectionRecords, "", errors.New("Bad pattern")},
+		{&options{outputFormat: "json", valueType: "single", regex: "("}, sampleSectionRecords, "", errors.New("Bad regex")},
+		{&options{outputFormat: "json", valueType: "single", prefix: "nothing."}, sampleSectionRecords, "{}\n", nil},
+		{&options{outputFormat: "json", valueType: "single", match: "*", as: "int"}, []string{"a=1", "b=2"}, `{"a":1,"b":2}` + "\n", nil},
+		{&options{outputFormat: "plain", valueType: "single", match: "*", client: txtkv.Options{Syntax: txtkv.SyntaxRFC6763}}, []string{"duplex", "model=x"},
+			"duplex\nmodel\tx\n", nil},
+		{&options{outputFormat: "json", valueType: "single", match: "*", client: txtkv.Options{Syntax: txtkv.SyntaxRFC6763}}, []string{"duplex", "model=x"},
+			`{"duplex":null,"model":"x"}` + "\n", nil},
+	} {
+		table := txtkv.ParseRecords(testPair.Records)
+		if testPair.Options.client.Syntax == txtkv.SyntaxRFC6763 {
+			table = txtkv.ParseAttributes(testPair.Records)
+		}
+		var outBuffer bytes.Buffer
+		entries, err := lookUpMap(testPair.Options, table)
+		if err == nil {
+			for i := range entries {
+				if testPair.Options.as != "" {
+					entries[i].values, _ = decodeValues(testPair.Options.as, entries[i].key, entries[i].values)
+				}
+			}
+			err = outputMap(testPair.Options, &outBuffer, entries)
+		}
+		result := outBuffer.String()
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if err == nil && result != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/govau/sdget/txtkv"
+	"github.com/pkg/errors"
+)
+
+func loadSchema(options *options, path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "error reading schema")
+	}
+	options.schema, err = txtkv.ParseSchema(data)
+	if err != nil {
+		return errors.Wrapf(err, "error loading schema from %s", path)
+	}
+	return nil
+}
+
+// With --schema, the whole record set is checked before anything is output
+func checkSchema(options *options, table *txtkv.Table, description string) {
+	if options.schema == nil {
+		return
+	}
+	if err := options.schema.Validate(table); err != nil {
+		message := fmt.Sprintf("Records in %s don't match the schema:\n", description)
+		for _, problem := range schemaProblems(err) {
+			message += fmt.Sprintf("  %s\n", problem)
+		}
+		fail(options, classSchema, err, description, "", "%s", message)
+	}
+}
+
+func schemaProblems(err error) []string {
+	if schemaErr, ok := err.(*txtkv.SchemaError); ok {
+		return schemaErr.Problems
+	}
+	return []string{err.Error()}
+}
+
+// JSON output is an object with "valid" and a list of "problems".  Plain and zero output have one problem per line.
+func outputLintProblems(options *options, sink io.Writer, problems []string) error {
+	if options.outputFormat == "json" {
+		if problems == nil {
+			problems = []string{}
+		}
+		encoder := json.NewEncoder(sink)
+		encoder.SetEscapeHTML(false)
+		result := struct {
+			Valid    bool     `json:"valid"`
+			Problems []string `json:"problems"`
+		}{len(problems) == 0, problems}
+		if err := encoder.Encode(result); err != nil {
+			return errors.Wrap(err, "error writing JSON")
+		}
+		return nil
+	}
+
+	terminator := "\n"
+	if options.outputFormat == "zero" {
+		terminator = "\000"
+	}
+	for _, problem := range problems {
+		if _, err := fmt.Fprint(sink, problem, terminator); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runLint(options *options, sources []string) {
+	table := lookUpTable(options, sources)
+
+	schema := options.schema
+	if schema == nil {
+		schema = &txtkv.Schema{}
+	}
+	var problems []string
+	if err := schema.Validate(table); err != nil {
+		problems = schemaProblems(err)
+	}
+
+	if err := outputLintProblems(options, os.Stdout, problems); err != nil {
+		fail(options, classOutput, err, "", "", "Error writing output values: %s\n", err.Error())
+	}
+	if len(problems) > 0 {
+		fail(options, classSchema, &txtkv.SchemaError{Problems: problems}, strings.Join(sources, ", "), "", "")
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+type outputLintProblemsTestPair struct {
+	Options  *options
+	Problems []string
+	Result   string
+}
+
+func TestOutputLintProblems(t *testing.T) {
+	problems := []string{"required key env is missing", "value \"three\" of key replicas is not an integer"}
+	for _, testPair := range []outputLintProblemsTestPair{
+		{&options{outputFormat: "plain"}, nil, ""},
+		{&options{outputFormat: "plain"}, problems, "required key env is missing\nvalue \"three\" of key replicas is not an integer\n"},
+		{&options{outputFormat: "zero"}, problems, "required key env is missing\000value \"three\" of key replicas is not an integer\000"},
+		{&options{outputFormat: "json"}, nil, `{"valid":true,"problems":[]}` + "\n"},
+		{&options{outputFormat: "json"}, problems[:1], `{"valid":false,"problems":["required key env is missing"]}` + "\n"},
+	} {
+		var buffer bytes.Buffer
+		if err := outputLintProblems(testPair.Options, &buffer, testPair.Problems); err != nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if buffer.String() != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", buffer.String(), "for", testPair)
+		}
+	}
+}
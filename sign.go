@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/govau/sdget/txtkv"
+	"github.com/pkg/errors"
+)
+
+func loadVerifyKeys(options *options, paths []string) error {
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, "error reading verification key")
+		}
+		keys, err := txtkv.ParsePublicKeys(data)
+		if err != nil {
+			return errors.Wrapf(err, "error loading verification keys from %s", path)
+		}
+		options.client.VerifyKeys = append(options.client.VerifyKeys, keys...)
+	}
+	return nil
+}
+
+// Signatures are valid from an hour ago, to allow for clocks that are behind
+const signatureClockSkew = time.Hour
+
+func runSign(options *options, source string, keyPaths []string, name string, validFor time.Duration) {
+	var keys []ed25519.PrivateKey
+	for _, path := range keyPaths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			fail(options, classConfig, err, "", "", "Error reading signing key: %s\n", err.Error())
+		}
+		key, err := txtkv.ParsePrivateKey(data)
+		if err != nil {
+			fail(options, classConfig, err, "", "", "Error loading signing key from %s: %s\n", path, err.Error())
+		}
+		keys = append(keys, key)
+	}
+
+	// The signature covers exactly the records of the source, not anything it includes
+	clientOptions := options.client
+	clientOptions.Includes = false
+	clientOptions.VerifyKeys = nil
+	client := txtkv.NewClient(clientOptions)
+	provider, err := client.Provider(source)
+	if err != nil {
+		fail(options, classConfig, err, source, "", "Error setting up client: %s\n", err.Error())
+	}
+	if name == "" {
+		name = txtkv.RecordName(provider)
+	}
+	if name == "" {
+		usageError(options, "--name is needed to sign %s, since it has no DNS name, try --help", source)
+	}
+	table, err := client.Table(context.Background(), source)
+	if err != nil {
+		fail(options, classSource, err, source, "", "Error looking up TXT records:\n%+v\n", err.Error())
+	}
+
+	now := time.Now()
+	var records []string
+	for _, key := range keys {
+		records = append(records, txtkv.SignTable(table, key, name, now.Add(-signatureClockSkew), now.Add(validFor)))
+	}
+	if err = outputRecords(options, os.Stdout, records); err != nil {
+		fail(options, classOutput, err, "", "", "Error writing TXT records: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/govau/sdget/txtkv"
+	"github.com/pkg/errors"
+)
+
+// The snapshot is written to a temporary file that's renamed into place, so that readers never see half a snapshot
+func runSnapshot(options *options, source string, outputPath string) {
+	client := txtkv.NewClient(options.client)
+	if _, err := client.Provider(source); err != nil {
+		fail(options, classConfig, err, source, "", "Error setting up client: %s\n", err.Error())
+	}
+	snapshot, err := client.Snapshot(context.Background(), source)
+	if err != nil {
+		fail(options, classSource, err, source, "", "Error taking snapshot of %s:\n%+v\n", source, err.Error())
+	}
+	if outputPath == "" {
+		if err := txtkv.WriteSnapshot(os.Stdout, snapshot); err != nil {
+			fail(options, classOutput, err, "", "", "Error writing snapshot: %s\n", err.Error())
+		}
+		return
+	}
+	if err := writeFileAtomically(outputPath, func(output io.Writer) error {
+		return txtkv.WriteSnapshot(output, snapshot)
+	}); err != nil {
+		fail(options, classOutput, err, "", "", "Error writing snapshot: %s\n", err.Error())
+	}
+}
+
+func writeFileAtomically(path string, write func(io.Writer) error) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return errors.Wrap(err, "error creating file")
+	}
+	defer os.Remove(file.Name())
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return errors.Wrapf(err, "error writing %s", file.Name())
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return errors.Wrapf(err, "error setting permissions of %s", file.Name())
+	}
+	return errors.Wrapf(os.Rename(file.Name(), path), "error renaming %s to %s", file.Name(), path)
+}
+
+// Dump output is the TXT strings of a source without the snapshot metadata, in a format that file sources can read
+// back: plain output is a file in the lines format, and JSON output is an array of strings
+func runDump(options *options, source string) {
+	client := txtkv.NewClient(options.client)
+	if _, err := client.Provider(source); err != nil {
+		fail(options, classConfig, err, source, "", "Error setting up client: %s\n", err.Error())
+	}
+	snapshot, err := client.Snapshot(context.Background(), source)
+	if err != nil {
+		fail(options, classSource, err, source, "", "Error looking up TXT records:\n%+v\n", err.Error())
+	}
+	if err := outputRecords(options, os.Stdout, snapshot.Records); err != nil {
+		fail(options, classOutput, err, "", "", "Error writing records: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,151 @@
+package txtkv
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Values that are too big for one TXT string can be split into chunks, with keys like "cert#0", "cert#1" and so on,
+// and a "cert#n" key with the number of chunks.  Only keys with a count are chunked, so that existing keys that happen
+// to look like chunks (like "server#1") are left alone, and missing chunks at the end can be detected.
+const (
+	ChunkSeparator = "#"
+	ChunkCountKey  = "n"
+)
+
+// MaxRecordLength is the size limit of a TXT character-string.
+const MaxRecordLength = 255
+
+// ChunkKey returns the key of chunk i of a chunked value.
+func ChunkKey(key string, i int) string {
+	return key + ChunkSeparator + strconv.Itoa(i)
+}
+
+// ReassembleChunks joins the chunks of a chunked value, in order.  isChunked is false if the key has no chunk count.
+//
+// The chunks must be numbered from 0 with no gaps, each must have exactly one value, they must all come from the
+// same source, and the count must match the number of chunks.  It's also an error for the key to have both chunks
+// and a plain value.  Errors match ErrInvalidChunks.
+func (t *Table) ReassembleChunks(key string) (value string, isChunked bool, err error) {
+	key = strings.ToLower(key)
+	prefix := key + ChunkSeparator
+	countValues, hasCount := t.values[prefix+ChunkCountKey]
+	if !hasCount {
+		return "", false, nil
+	}
+	var indices []int
+	for _, chunkKey := range t.keys {
+		if !strings.HasPrefix(chunkKey, prefix) {
+			continue
+		}
+		// Other suffixes (like "cert#sha256") aren't chunks
+		if i, isIndex := chunkIndex(chunkKey[len(prefix):]); isIndex {
+			indices = append(indices, i)
+		}
+	}
+
+	if _, ok := t.values[key]; ok {
+		return "", true, fmt.Errorf("%w: key %s has both a value and chunks", ErrInvalidChunks, key)
+	}
+	if len(countValues) != 1 {
+		return "", true, fmt.Errorf("%w: %d values found for chunk count %s%s", ErrInvalidChunks, len(countValues), prefix, ChunkCountKey)
+	}
+	count, err := strconv.Atoi(countValues[0])
+	if err != nil || count < 1 {
+		return "", true, fmt.Errorf("%w: invalid chunk count \"%s\" for key %s", ErrInvalidChunks, countValues[0], key)
+	}
+	if count != len(indices) {
+		return "", true, fmt.Errorf("%w: expected %d chunks for key %s but found %d", ErrInvalidChunks, count, key, len(indices))
+	}
+
+	sort.Ints(indices)
+	var builder strings.Builder
+	var source string
+	for i, index := range indices {
+		if index != i {
+			return "", true, fmt.Errorf("%w: chunk %d of key %s is missing", ErrInvalidChunks, i, key)
+		}
+		chunkKey := ChunkKey(key, i)
+		if len(t.values[chunkKey]) != 1 {
+			return "", true, fmt.Errorf("%w: %d values found for chunk %s", ErrInvalidChunks, len(t.values[chunkKey]), chunkKey)
+		}
+		if i == 0 {
+			source = t.sources[chunkKey][0]
+		} else if t.sources[chunkKey][0] != source {
+			return "", true, fmt.Errorf("%w: chunks of key %s come from different sources (%s and %s)", ErrInvalidChunks, key, source, t.sources[chunkKey][0])
+		}
+		builder.WriteString(t.values[chunkKey][0])
+	}
+	return builder.String(), true, nil
+}
+
+// Chunk numbers are decimal, without leading zeros
+func chunkIndex(suffix string) (int, bool) {
+	i, err := strconv.Atoi(suffix)
+	return i, err == nil && i >= 0 && strconv.Itoa(i) == suffix
+}
+
+// LogicalKeys returns the keys of a table with chunks (like "cert#0" and "cert#n") counted as their base key ("cert"),
+// and without reserved keys (IncludeKey and SignatureKey), sorted.
+func (t *Table) LogicalKeys() []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, key := range t.Keys() {
+		if i := strings.LastIndex(key, ChunkSeparator); i >= 0 {
+			base, suffix := key[:i], key[i+len(ChunkSeparator):]
+			if _, isIndex := chunkIndex(suffix); suffix == ChunkCountKey || (isIndex && t.Has(base+ChunkSeparator+ChunkCountKey)) {
+				key = base
+			}
+		}
+		if key == IncludeKey || key == SignatureKey || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, key)
+	}
+	return result
+}
+
+// EncodeRecord makes a TXT string from a key and value, escaping the key as needed for SplitRecord.
+func EncodeRecord(key string, value string) string {
+	var builder strings.Builder
+	for i, c := range []byte(key) {
+		edge := i == 0 || i == len(key)-1
+		if c == '`' || c == '=' || (edge && (c == ' ' || c == '\t')) {
+			builder.WriteByte('`')
+		}
+		builder.WriteByte(c)
+	}
+	builder.WriteByte('=')
+	builder.WriteString(value)
+	return builder.String()
+}
+
+// ChunkRecords makes TXT strings for a key and value, each no longer than maxLength bytes.  Values that fit are a
+// single record.  Bigger values are split into chunks, followed by a chunk count record.
+func ChunkRecords(key string, value string, maxLength int) ([]string, error) {
+	if record := EncodeRecord(key, value); len(record) <= maxLength {
+		return []string{record}, nil
+	}
+
+	var records []string
+	for i := 0; len(value) > 0; i++ {
+		prefix := EncodeRecord(ChunkKey(key, i), "")
+		size := maxLength - len(prefix)
+		if size < 1 {
+			return nil, fmt.Errorf("maximum record length %d is too short for key %s", maxLength, key)
+		}
+		if size > len(value) {
+			size = len(value)
+		}
+		records = append(records, prefix+value[:size])
+		value = value[size:]
+	}
+	countRecord := EncodeRecord(key+ChunkSeparator+ChunkCountKey, strconv.Itoa(len(records)))
+	if len(countRecord) > maxLength {
+		return nil, fmt.Errorf("maximum record length %d is too short for key %s", maxLength, key)
+	}
+	return append(records, countRecord), nil
+}
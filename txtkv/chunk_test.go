@@ -0,0 +1,116 @@
+package txtkv
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type reassembleChunksTestPair struct {
+	Records   []string
+	Key       string
+	Value     string
+	IsChunked bool
+	Err       error
+}
+
+func TestReassembleChunks(t *testing.T) {
+	for _, testPair := range []reassembleChunksTestPair{
+		{[]string{"cert#1=world", "cert#0=hello ", "cert#n=2"}, "Cert", "hello world", true, nil},
+		{[]string{"cert#0=a", "cert#1=b", "cert#2=c", "cert#n=3"}, "cert", "abc", true, nil},
+		{[]string{"cert=plain"}, "cert", "", false, nil},
+		{[]string{"cert#0=a", "cert#1=b"}, "cert", "", false, nil},
+		{[]string{"cert=plain", "cert#0=a"}, "cert", "", false, nil},
+		{[]string{"cert#sha256=abc", "other#0=x"}, "cert", "", false, nil},
+		{[]string{"cert#0=a", "cert#2=c", "cert#n=2"}, "cert", "", true, errors.New("Gap")},
+		{[]string{"cert#1=b", "cert#n=1"}, "cert", "", true, errors.New("Gap")},
+		{[]string{"cert#0=a", "cert#0=b", "cert#n=1"}, "cert", "", true, errors.New("Duplicate")},
+		{[]string{"cert#0=a", "cert#01=b", "cert#n=1"}, "cert", "a", true, nil},
+		{[]string{"cert#0=a", "cert#1=b", "cert#n=3"}, "cert", "", true, errors.New("Missing last chunk")},
+		{[]string{"cert#0=a", "cert#n=many"}, "cert", "", true, errors.New("Invalid count")},
+		{[]string{"cert#0=a", "cert#n=1", "cert#n=1"}, "cert", "", true, errors.New("Duplicate count")},
+		{[]string{"cert#n=1"}, "cert", "", true, errors.New("No chunks")},
+		{[]string{"cert=plain", "cert#0=a", "cert#n=1"}, "cert", "", true, errors.New("Ambiguous")},
+	} {
+		value, isChunked, err := ParseRecords(testPair.Records).ReassembleChunks(testPair.Key)
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if err != nil && !errors.Is(err, ErrInvalidChunks) {
+			t.Error("Expected ErrInvalidChunks but got", err, "for", testPair)
+		}
+
+		if value != testPair.Value || isChunked != testPair.IsChunked {
+			t.Error("Expected", testPair.Value, testPair.IsChunked, "but got", value, isChunked, "for", testPair)
+		}
+	}
+
+	first := parseRecordsFrom([]string{"cert#0=a", "cert#n=2"}, "first", SyntaxRFC1464)
+	second := parseRecordsFrom([]string{"cert#1=b"}, "second", SyntaxRFC1464)
+	merged, _ := MergeTables(LayerFirstWins, first, second)
+	if _, _, err := merged.ReassembleChunks("cert"); !errors.Is(err, ErrInvalidChunks) {
+		t.Error("Expected ErrInvalidChunks for chunks from different sources but got", err)
+	}
+}
+
+type encodeRecordTestPair struct {
+	Key    string
+	Value  string
+	Record string
+}
+
+func TestLogicalKeys(t *testing.T) {
+	table := ParseRecords([]string{"cert#1=b", "cert#0=a", "cert#n=2", "cert#sha256=abc", "server#1=a", "Name=web", "sdget-include=dns:other", "sdget-signature=x"})
+	expected := []string{"cert", "cert#sha256", "name", "server#1"}
+	if keys := table.LogicalKeys(); !reflect.DeepEqual(keys, expected) {
+		t.Error("Expected", expected, "but got", keys)
+	}
+}
+
+func TestEncodeRecord(t *testing.T) {
+	for _, testPair := range []encodeRecordTestPair{
+		{"foo", "bar", "foo=bar"},
+		{"a=b", "c=d", "a`=b=c=d"},
+		{"back`tick", "", "back``tick="},
+		{" spaced key ", "value", "` spaced key` =value"},
+	} {
+		record := EncodeRecord(testPair.Key, testPair.Value)
+		if record != testPair.Record {
+			t.Error("Expected", testPair.Record, "but got", record, "for", testPair)
+		}
+		if _, key, value := SplitRecord(record); key != strings.ToLower(testPair.Key) || value != testPair.Value {
+			t.Error("Expected round trip but got", key, value, "for", testPair)
+		}
+	}
+}
+
+func TestChunkRecords(t *testing.T) {
+	records, err := ChunkRecords("key", "value", MaxRecordLength)
+	if expected := []string{"key=value"}; err != nil || !reflect.DeepEqual(records, expected) {
+		t.Error("Expected", expected, "but got", records, err)
+	}
+
+	records, err = ChunkRecords("key", "abcdefghijklmnopqrstuvwxyz", 10)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	expected := []string{"key#0=abcd", "key#1=efgh", "key#2=ijkl", "key#3=mnop", "key#4=qrst", "key#5=uvwx", "key#6=yz", "key#n=7"}
+	if !reflect.DeepEqual(records, expected) {
+		t.Error("Expected", expected, "but got", records)
+	}
+	value, isChunked, err := ParseRecords(records).ReassembleChunks("key")
+	if err != nil || !isChunked || value != "abcdefghijklmnopqrstuvwxyz" {
+		t.Error("Expected round trip but got", value, isChunked, err)
+	}
+
+	if _, err := ChunkRecords("a-very-long-key", "abcdefghijklmnopqrstuvwxyz", 10); err == nil {
+		t.Error("Expected error for maximum length shorter than the key")
+	}
+}
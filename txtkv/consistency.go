@@ -0,0 +1,328 @@
+package txtkv
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Server statuses in a ConsistencyReport
+const (
+	StatusOK        = "ok"
+	StatusLagging   = "lagging"
+	StatusDivergent = "divergent"
+	StatusError     = "error"
+)
+
+// ConsistencyReport compares the TXT records of a domain across all authoritative nameservers of its zone.
+type ConsistencyReport struct {
+	Domain string
+	Zone   string
+	// Serial is the newest SOA serial of the zone seen on any nameserver.
+	Serial  uint32
+	Servers []*ServerStatus
+}
+
+// ServerStatus is the state of one authoritative nameserver in a ConsistencyReport.
+//
+// The first nameserver with the newest serial is the reference.  Nameservers with an older serial are
+// StatusLagging, and nameservers with the newest serial but different key/value pairs are StatusDivergent.
+// DifferingKeys lists the keys with values that differ from the reference.  Nameservers that can't be queried
+// (including ones with no address, which is then empty) are StatusError, with Err saying why.
+type ServerStatus struct {
+	Name          string
+	Address       string
+	Serial        uint32
+	Status        string
+	DifferingKeys []string
+	Err           error
+
+	records map[string][]string
+}
+
+// Consistent is true if every nameserver is StatusOK.
+func (r *ConsistencyReport) Consistent() bool {
+	for _, server := range r.Servers {
+		if server.Status != StatusOK {
+			return false
+		}
+	}
+	return true
+}
+
+type authoritativeServer struct {
+	name    string
+	address string
+	// err is why the nameserver's address couldn't be found, if it couldn't
+	err error
+}
+
+// CheckConsistency finds the zone containing a domain and its NS records, then queries every authoritative
+// nameserver directly (without recursion) to compare SOA serials and the key/value pairs of the domain's TXT records.
+// Recursive resolvers only ever give one answer, which hides secondary nameservers that are serving stale records.
+// Like DNS sources, domains without a trailing dot are relative to Options.BaseDomain.
+func (c *Client) CheckConsistency(ctx context.Context, domain string) (*ConsistencyReport, error) {
+	ctx = c.traceContext(ctx)
+	domain = qualifyDomain(domain, c.options.BaseDomain)
+	resolve, err := makeResolveFunc(&c.options)
+	if err != nil {
+		return nil, err
+	}
+	zone, servers, err := discoverAuthoritativeServers(ctx, resolve, domain)
+	if err != nil {
+		return nil, err
+	}
+	return checkServers(ctx, domain, zone, servers), nil
+}
+
+// Discovery of the authoritative nameservers goes through the same kind of resolution as normal lookups
+type resolveFunc func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error)
+
+func makeResolveFunc(options *Options) (resolveFunc, error) {
+	if options.Resolve == ResolveIterative {
+		resolver, err := makeIterativeResolver(options)
+		if err != nil {
+			return nil, fmt.Errorf("error configuring iterative resolver: %w", err)
+		}
+		return resolver.resolve, nil
+	}
+	nameserver, err := canonicalNameserver(options, "")
+	if err != nil {
+		return nil, fmt.Errorf("error configuring DNS client: %w", err)
+	}
+	return func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+		return recursiveQuery(ctx, nameserver, name, qtype)
+	}, nil
+}
+
+func discoverAuthoritativeServers(ctx context.Context, resolve resolveFunc, domain string) (string, []authoritativeServer, error) {
+	zone, err := findZone(ctx, resolve, domain)
+	if err != nil {
+		return "", nil, err
+	}
+
+	response, err := resolve(ctx, zone, dns.TypeNS)
+	if err != nil {
+		return "", nil, fmt.Errorf("error looking up NS records for zone %s: %w", zone, err)
+	}
+	var names []string
+	for _, answer := range response.Answer {
+		if ns, ok := answer.(*dns.NS); ok {
+			names = append(names, ns.Ns)
+		}
+	}
+	if len(names) == 0 {
+		return "", nil, fmt.Errorf("no NS records for zone %s", zone)
+	}
+	sort.Strings(names)
+
+	var servers []authoritativeServer
+	for _, name := range names {
+		addresses, err := lookUpAddresses(ctx, resolve, name)
+		if err != nil {
+			// The other nameservers can still be checked
+			servers = append(servers, authoritativeServer{name: name, err: err})
+			continue
+		}
+		for _, address := range addresses {
+			servers = append(servers, authoritativeServer{
+				name:    name,
+				address: net.JoinHostPort(address, "53"),
+			})
+		}
+	}
+	return zone, servers, nil
+}
+
+// The SOA record of the enclosing zone comes back in the answer section for a zone apex, and in the authority section
+// for any other name (whether it exists or not).
+func findZone(ctx context.Context, resolve resolveFunc, domain string) (string, error) {
+	soa, err := findSOA(ctx, resolve, domain)
+	if err != nil {
+		return "", err
+	}
+	return soa.Hdr.Name, nil
+}
+
+func findSOA(ctx context.Context, resolve resolveFunc, domain string) (*dns.SOA, error) {
+	response, err := resolve(ctx, domain, dns.TypeSOA)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up zone for %s: %w", domain, err)
+	}
+	for _, section := range [][]dns.RR{response.Answer, response.Ns} {
+		for _, rr := range section {
+			if soa, ok := rr.(*dns.SOA); ok {
+				return soa, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no SOA record found for %s", domain)
+}
+
+func lookUpAddresses(ctx context.Context, resolve resolveFunc, name string) ([]string, error) {
+	var addresses []string
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		response, err := resolve(ctx, name, qtype)
+		if err != nil {
+			return nil, fmt.Errorf("error looking up address of nameserver %s: %w", name, err)
+		}
+		for _, answer := range response.Answer {
+			switch rr := answer.(type) {
+			case *dns.A:
+				addresses = append(addresses, rr.A.String())
+			case *dns.AAAA:
+				addresses = append(addresses, rr.AAAA.String())
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no addresses found for nameserver %s", name)
+	}
+	return addresses, nil
+}
+
+func recursiveQuery(ctx context.Context, resolver string, name string, qtype uint16) (*dns.Msg, error) {
+	query := new(dns.Msg)
+	query.SetQuestion(name, qtype)
+	query.RecursionDesired = true
+	response, err := exchange(ctx, query, resolver)
+	if err != nil {
+		return nil, err
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("%w: %s", ErrServerFailure, dns.RcodeToString[response.Rcode])
+	}
+	return response, nil
+}
+
+func authoritativeQuery(ctx context.Context, server string, name string, qtype uint16) (*dns.Msg, error) {
+	query := new(dns.Msg)
+	query.SetQuestion(name, qtype)
+	query.RecursionDesired = false
+	response, err := exchange(ctx, query, server)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Authoritative {
+		return nil, fmt.Errorf("server is not authoritative for %s", name)
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("%w: %s", ErrServerFailure, dns.RcodeToString[response.Rcode])
+	}
+	return response, nil
+}
+
+func checkServers(ctx context.Context, domain string, zone string, servers []authoritativeServer) *ConsistencyReport {
+	report := &ConsistencyReport{
+		Domain: domain,
+		Zone:   zone,
+	}
+	for _, server := range servers {
+		report.Servers = append(report.Servers, queryServer(ctx, domain, zone, server))
+	}
+
+	var reference *ServerStatus
+	for _, status := range report.Servers {
+		if status.Err != nil {
+			continue
+		}
+		if reference == nil || serialLess(reference.Serial, status.Serial) {
+			reference = status
+		}
+	}
+	if reference != nil {
+		report.Serial = reference.Serial
+	}
+
+	for _, status := range report.Servers {
+		if status.Err != nil {
+			status.Status = StatusError
+			continue
+		}
+		status.DifferingKeys = differingKeys(reference.records, status.records)
+		switch {
+		case status.Serial != reference.Serial:
+			status.Status = StatusLagging
+		case len(status.DifferingKeys) > 0:
+			status.Status = StatusDivergent
+		default:
+			status.Status = StatusOK
+		}
+	}
+	return report
+}
+
+func queryServer(ctx context.Context, domain string, zone string, server authoritativeServer) *ServerStatus {
+	status := &ServerStatus{
+		Name:    server.name,
+		Address: server.address,
+	}
+	if server.err != nil {
+		status.Err = server.err
+		return status
+	}
+
+	response, err := authoritativeQuery(ctx, server.address, zone, dns.TypeSOA)
+	if err != nil {
+		status.Err = fmt.Errorf("error querying SOA record for %s: %w", zone, err)
+		return status
+	}
+	// 0 is a valid serial, so it can't mean there's no SOA record
+	hasSOA := false
+	for _, answer := range response.Answer {
+		if soa, ok := answer.(*dns.SOA); ok {
+			status.Serial = soa.Serial
+			hasSOA = true
+		}
+	}
+	if !hasSOA {
+		status.Err = fmt.Errorf("no SOA record for %s", zone)
+		return status
+	}
+
+	response, err = authoritativeQuery(ctx, server.address, domain, dns.TypeTXT)
+	if err != nil {
+		status.Err = fmt.Errorf("error querying TXT records for %s: %w", domain, err)
+		return status
+	}
+	txtRecords, err := txtAnswers(response)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	status.records = keyValues(txtRecords)
+	return status
+}
+
+func keyValues(txtRecords []string) map[string][]string {
+	result := ParseRecords(txtRecords).Map()
+	for _, values := range result {
+		sort.Strings(values)
+	}
+	return result
+}
+
+func differingKeys(reference map[string][]string, records map[string][]string) []string {
+	var result []string
+	for key, values := range reference {
+		if strings.Join(values, "\000") != strings.Join(records[key], "\000") || len(values) != len(records[key]) {
+			result = append(result, key)
+		}
+	}
+	for key := range records {
+		if _, ok := reference[key]; !ok {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Zone serials use the sequence space arithmetic from https://tools.ietf.org/html/rfc1982
+func serialLess(s1 uint32, s2 uint32) bool {
+	return (s1 < s2 && s2-s1 < 1<<31) || (s1 > s2 && s1-s2 > 1<<31)
+}
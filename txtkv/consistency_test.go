@@ -0,0 +1,239 @@
+package txtkv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// Starts an in-process DNS server listening on TCP that serves the given records
+// Queries for names below an NS record get a referral (with glue), like from a parent zone.
+func startTestServer(t *testing.T, address string, records []string) string {
+	var rrs []dns.RR
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatal("Error", err.Error(), "parsing test record", record)
+		}
+		rrs = append(rrs, rr)
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	server := &dns.Server{
+		Listener: listener,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, query *dns.Msg) {
+			w.WriteMsg(testServerResponse(rrs, query))
+		}),
+	}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return listener.Addr().String()
+}
+
+func testServerResponse(rrs []dns.RR, query *dns.Msg) *dns.Msg {
+	response := new(dns.Msg)
+	response.SetReply(query)
+	question := query.Question[0]
+
+	nameExists := false
+	for _, rr := range rrs {
+		if !strings.EqualFold(rr.Header().Name, question.Name) {
+			continue
+		}
+		nameExists = true
+		if rr.Header().Rrtype == question.Qtype || rr.Header().Rrtype == dns.TypeCNAME {
+			response.Answer = append(response.Answer, rr)
+		}
+	}
+
+	if len(response.Answer) == 0 {
+		var delegation string
+		for _, rr := range rrs {
+			owner := rr.Header().Name
+			if rr.Header().Rrtype == dns.TypeNS && dns.IsSubDomain(owner, question.Name) && len(owner) > len(delegation) {
+				delegation = owner
+			}
+		}
+		if delegation != "" {
+			for _, rr := range rrs {
+				if ns, ok := rr.(*dns.NS); ok && ns.Hdr.Name == delegation {
+					response.Ns = append(response.Ns, ns)
+					for _, glue := range rrs {
+						if glue.Header().Name == ns.Ns && (glue.Header().Rrtype == dns.TypeA || glue.Header().Rrtype == dns.TypeAAAA) {
+							response.Extra = append(response.Extra, glue)
+						}
+					}
+				}
+			}
+			return response
+		}
+	}
+
+	response.Authoritative = true
+	if !nameExists {
+		response.Rcode = dns.RcodeNameError
+	}
+	return response
+}
+
+func TestCheckServers(t *testing.T) {
+	primary := startTestServer(t, "127.0.0.1:0", []string{
+		"example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 2019010302 3600 600 86400 300",
+		`foo.example.com. 300 IN TXT "a=1" "23"`,
+		`foo.example.com. 300 IN TXT "b=2"`,
+	})
+	inSync := startTestServer(t, "127.0.0.1:0", []string{
+		"example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 2019010302 3600 600 86400 300",
+		`foo.example.com. 300 IN TXT "B=2"`,
+		`foo.example.com. 300 IN TXT "a=123"`,
+	})
+	lagging := startTestServer(t, "127.0.0.1:0", []string{
+		"example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 2019010301 3600 600 86400 300",
+		`foo.example.com. 300 IN TXT "a=1"`,
+		`foo.example.com. 300 IN TXT "b=2"`,
+	})
+	divergent := startTestServer(t, "127.0.0.1:0", []string{
+		"example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 2019010302 3600 600 86400 300",
+		`foo.example.com. 300 IN TXT "a=123"`,
+		`foo.example.com. 300 IN TXT "c=3"`,
+	})
+
+	report := checkServers(context.Background(), "foo.example.com.", "example.com.", []authoritativeServer{
+		{name: "ns1.example.com.", address: primary},
+		{name: "ns2.example.com.", address: inSync},
+		{name: "ns3.example.com.", address: lagging},
+		{name: "ns4.example.com.", address: divergent},
+	})
+
+	if report.Consistent() {
+		t.Error("Expected inconsistent report")
+	}
+	if report.Serial != 2019010302 {
+		t.Error("Expected serial 2019010302 but got", report.Serial)
+	}
+	expectedStatuses := []string{"ok", "ok", "lagging", "divergent"}
+	var statuses []string
+	for _, status := range report.Servers {
+		if status.Err != nil {
+			t.Error("Error", status.Err.Error(), "for", status.Name)
+		}
+		statuses = append(statuses, status.Status)
+	}
+	if !reflect.DeepEqual(statuses, expectedStatuses) {
+		t.Error("Expected", expectedStatuses, "but got", statuses)
+	}
+	if expected := []string{"a"}; !reflect.DeepEqual(report.Servers[2].DifferingKeys, expected) {
+		t.Error("Expected", expected, "but got", report.Servers[2].DifferingKeys)
+	}
+	if expected := []string{"b", "c"}; !reflect.DeepEqual(report.Servers[3].DifferingKeys, expected) {
+		t.Error("Expected", expected, "but got", report.Servers[3].DifferingKeys)
+	}
+}
+
+func TestCheckServersZeroSerial(t *testing.T) {
+	records := []string{
+		"example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 0 3600 600 86400 300",
+		`foo.example.com. 300 IN TXT "a=1"`,
+	}
+	noSOA := startTestServer(t, "127.0.0.1:0", records[1:])
+	report := checkServers(context.Background(), "foo.example.com.", "example.com.", []authoritativeServer{
+		{name: "ns1.example.com.", address: startTestServer(t, "127.0.0.1:0", records)},
+		{name: "ns2.example.com.", address: startTestServer(t, "127.0.0.1:0", records)},
+		{name: "ns3.example.com.", address: noSOA},
+	})
+	expectedStatuses := []string{"ok", "ok", "error"}
+	var statuses []string
+	for _, status := range report.Servers {
+		statuses = append(statuses, status.Status)
+	}
+	if !reflect.DeepEqual(statuses, expectedStatuses) {
+		t.Error("Expected", expectedStatuses, "but got", statuses)
+	}
+}
+
+func TestDiscoverAuthoritativeServers(t *testing.T) {
+	var rrs []dns.RR
+	for _, record := range []string{
+		"example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 1 3600 600 86400 300",
+		"example.com. 300 IN NS ns1.example.com.",
+		"example.com. 300 IN NS ns2.example.com.",
+		"ns1.example.com. 300 IN A 192.0.2.1",
+	} {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatal("Error", err.Error(), "parsing test record", record)
+		}
+		rrs = append(rrs, rr)
+	}
+	resolve := func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+		if name == "ns2.example.com." {
+			return nil, ErrNetwork
+		}
+		query := new(dns.Msg)
+		query.SetQuestion(name, qtype)
+		return testServerResponse(rrs, query), nil
+	}
+
+	zone, servers, err := discoverAuthoritativeServers(context.Background(), resolve, "example.com.")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if zone != "example.com." || len(servers) != 2 {
+		t.Fatal("Expected 2 nameservers for example.com. but got", zone, servers)
+	}
+	if servers[0].name != "ns1.example.com." || servers[0].address != "192.0.2.1:53" || servers[0].err != nil {
+		t.Error("Expected ns1.example.com. at 192.0.2.1:53 but got", servers[0])
+	}
+	if servers[1].name != "ns2.example.com." || !errors.Is(servers[1].err, ErrNetwork) {
+		t.Error("Expected a network error for ns2.example.com. but got", servers[1])
+	}
+
+	report := checkServers(context.Background(), "foo.example.com.", zone, servers[1:])
+	if status := report.Servers[0]; status.Status != StatusError || !errors.Is(status.Err, ErrNetwork) {
+		t.Error("Expected ns2.example.com. to be an error but got", status.Status, status.Err)
+	}
+}
+
+func TestCheckConsistencyBaseDomain(t *testing.T) {
+	address := startTestServer(t, "127.0.0.1:0", []string{
+		"foo.example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 1 3600 600 86400 300",
+		"foo.example.com. 300 IN NS ns1.example.com.",
+	})
+	client := NewClient(Options{Nameserver: address, BaseDomain: "example.com"})
+
+	report, err := client.CheckConsistency(context.Background(), "foo")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if report.Domain != "foo.example.com." || report.Zone != "foo.example.com." {
+		t.Error("Expected zone foo.example.com. but got", report.Domain, report.Zone)
+	}
+}
+
+type serialLessTestPair struct {
+	S1     uint32
+	S2     uint32
+	Result bool
+}
+
+func TestSerialLess(t *testing.T) {
+	for _, testPair := range []serialLessTestPair{
+		{1, 2, true},
+		{2, 1, false},
+		{1, 1, false},
+		{4294967295, 0, true},
+		{0, 4294967295, false},
+	} {
+		if result := serialLess(testPair.S1, testPair.S2); result != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package txtkv
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Files of resource records, like the full output of dig, zone file lines, or nsupdate scripts, are "dig" files.  The
+// output of "host -t txt" is a "host" file.  Only the TXT records are used, and everything else (like comments,
+// other record types and nsupdate commands other than "update add") is skipped.
+
+// "foo.example.com descriptive text "foo=bar" "more""
+var hostTxtLine = regexp.MustCompile(`^(\S+) descriptive text (.*)$`)
+
+// nsupdate commands that don't add records
+var nsupdateCommands = map[string]bool{
+	"server": true, "local": true, "zone": true, "class": true, "ttl": true, "key": true, "gsstsig": true,
+	"oldgsstsig": true, "realm": true, "prereq": true, "update": true, "show": true, "send": true, "answer": true,
+	"debug": true, "quit": true,
+}
+
+// detectFileFormat recognises the full output of dig by the "; <<>> DiG" line at the start.  Everything else is the
+// lines format, since any line could be a raw record (like "server = db1"), so the other formats must be chosen with
+// the format parameter.
+func detectFileFormat(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "; <<>> DiG ") {
+			return fileFormatDig
+		}
+		return fileFormatLines
+	}
+	return fileFormatLines
+}
+
+// Lines of nsupdate scripts that add records become plain resource records, and the other commands are dropped
+func nsupdateToZone(data []byte) string {
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && fields[0] == "update" && fields[1] == "add" {
+			line = strings.Join(fields[2:], " ")
+		} else if len(fields) > 0 && nsupdateCommands[fields[0]] {
+			continue
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// host output becomes TXT resource records, and lines that aren't TXT records (like "Host foo not found") are dropped
+func hostToZone(data []byte) string {
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if match := hostTxtLine.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			lines = append(lines, dns.Fqdn(match[1])+" IN TXT "+match[2])
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseZoneRecords reads the TXT records of one owner name from resource records.  Without an owner, the records
+// must all have the same owner.  The strings of each record are joined like the answers to DNS queries (or kept
+// separate with --syntax rfc6763).
+func parseZoneRecords(zone string, owner string, syntax string) ([]string, error) {
+	parser := dns.NewZoneParser(strings.NewReader(zone), ".", "")
+	parser.SetDefaultTTL(0)
+	answers := new(dns.Msg)
+	owners := make(map[string]bool)
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		txt, isTxt := rr.(*dns.TXT)
+		if !isTxt {
+			continue
+		}
+		owners[strings.ToLower(txt.Hdr.Name)] = true
+		if owner == "" || strings.EqualFold(txt.Hdr.Name, dns.Fqdn(owner)) {
+			answers.Answer = append(answers.Answer, txt)
+		}
+	}
+	if err := parser.Err(); err != nil {
+		return nil, fmt.Errorf("invalid resource record: %w", err)
+	}
+	if owner == "" && len(owners) > 1 {
+		names := make([]string, 0, len(owners))
+		for name := range owners {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("TXT records for more than one name (%s), choose one with the owner parameter", strings.Join(names, ", "))
+	}
+	if owner != "" && !owners[strings.ToLower(dns.Fqdn(owner))] {
+		return nil, fmt.Errorf("%w for %s in the file", ErrNoRecords, dns.Fqdn(owner))
+	}
+
+	var records []string
+	var err error
+	if syntax == SyntaxRFC6763 {
+		records, err = txtStrings(answers)
+	} else {
+		records, err = txtAnswers(answers)
+	}
+	if records == nil && err == nil {
+		records = []string{}
+	}
+	return records, err
+}
@@ -0,0 +1,396 @@
+package txtkv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type dnsProvider struct {
+	options    *Options
+	nameserver string
+	// Where the nameserver came from, for tracing
+	nameserverOrigin string
+	domain           string
+	resolver         *iterativeResolver
+	timeout          time.Duration
+	dnssec           bool
+	tsig             *TSIGKey
+}
+
+func init() {
+	RegisterScheme("dns", Scheme{
+		Components: ComponentAuthority | ComponentQuery,
+		Params:     []string{"class", "type", "timeout", "dnssec"},
+		New:        newDnsURIProvider,
+		Network:    true,
+	})
+}
+
+// DNS URIs are described in https://tools.ietf.org/html/rfc4501
+// The class and type parameters from the RFC are accepted, but can only be IN and TXT.
+func newDnsURIProvider(options *Options, uri *URI) (Provider, error) {
+	if class, ok := uri.param("class"); ok && !strings.EqualFold(class, "IN") {
+		return nil, fmt.Errorf("unsupported DNS class %s: only IN is supported", class)
+	}
+	if qtype, ok := uri.param("type"); ok && !strings.EqualFold(qtype, "TXT") {
+		return nil, fmt.Errorf("unsupported DNS record type %s: only TXT is supported", qtype)
+	}
+
+	provider, err := makeDnsProvider(options, uri.Authority, strings.TrimPrefix(uri.Path, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout, ok := uri.param("timeout"); ok {
+		provider.timeout, err = time.ParseDuration(timeout)
+		if err != nil || provider.timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout \"%s\": must be a positive duration like 2s", timeout)
+		}
+	}
+
+	if dnssec, ok := uri.param("dnssec"); ok {
+		provider.dnssec, err = parseBoolParam(dnssec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dnssec parameter: %w", err)
+		}
+		if provider.dnssec && provider.resolver != nil {
+			return nil, errors.New("dnssec needs a validating recursive resolver, so it can't be used with iterative resolution")
+		}
+	}
+
+	return provider, nil
+}
+
+// A parameter given without a value (like "?dnssec") is true
+func parseBoolParam(value string) (bool, error) {
+	if value == "" {
+		return true, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func makeDnsProvider(options *Options, nameserver string, domain string) (*dnsProvider, error) {
+	if domain == "" {
+		return nil, errors.New("non-empty domain name required")
+	}
+	domain = qualifyDomain(domain, options.BaseDomain)
+	if nameserver == "" && options.Resolve == ResolveIterative {
+		if options.DNSSEC {
+			return nil, errors.New("dnssec needs a validating recursive resolver, so it can't be used with iterative resolution")
+		}
+		if options.TSIGKey != nil {
+			return nil, errors.New("a TSIG key is shared with one nameserver, so it can't be used with iterative resolution")
+		}
+		resolver, err := makeIterativeResolver(options)
+		if err != nil {
+			return nil, fmt.Errorf("error configuring iterative resolver: %w", err)
+		}
+		return &dnsProvider{
+			options:  options,
+			domain:   domain,
+			resolver: resolver,
+		}, nil
+	}
+	origin := "from the source"
+	if nameserver == "" && options.Nameserver != "" {
+		origin = "default nameserver"
+	} else if nameserver == "" {
+		origin = "from /etc/resolv.conf"
+	}
+	nameserver, err := canonicalNameserver(options, nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring DNS client: %w", err)
+	}
+	return &dnsProvider{
+		options:          options,
+		nameserver:       nameserver,
+		nameserverOrigin: origin,
+		domain:           domain,
+		dnssec:           options.DNSSEC,
+		tsig:             options.TSIGKey,
+	}, nil
+}
+
+// Names without a trailing dot are relative to the base domain (or the root, if there isn't one)
+func qualifyDomain(domain string, baseDomain string) string {
+	if strings.HasSuffix(domain, ".") {
+		return domain
+	}
+	baseDomain = strings.Trim(baseDomain, ".")
+	if baseDomain == "" {
+		return domain + "."
+	}
+	return domain + "." + baseDomain + "."
+}
+
+func (d *dnsProvider) TxtRecords(ctx context.Context) ([]string, error) {
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+	response, err := d.query(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return d.txtRecords(response)
+}
+
+func (d *dnsProvider) recordName() string {
+	return d.domain
+}
+
+// DNS sources are the same if they query the same name in the same way
+func (d *dnsProvider) fetchKey() string {
+	return fmt.Sprintf("dns://%s/%s?timeout=%s&dnssec=%t", d.nameserver, strings.ToLower(d.domain), d.timeout, d.dnssec)
+}
+
+func (d *dnsProvider) txtRecords(response *dns.Msg) ([]string, error) {
+	if d.options.Syntax == SyntaxRFC6763 {
+		return txtStrings(response)
+	}
+	return txtAnswers(response)
+}
+
+// query looks up the TXT records, and checks the response code (and authentication, if needed)
+func (d *dnsProvider) query(ctx context.Context) (*dns.Msg, error) {
+	var response *dns.Msg
+	var err error
+	if d.resolver != nil {
+		tracef(ctx, "resolving %s iteratively, starting from the root servers", d.domain)
+		response, err = d.resolver.resolve(ctx, d.domain, dns.TypeTXT)
+	} else {
+		tracef(ctx, "using nameserver %s (%s)", d.nameserver, d.nameserverOrigin)
+		query := new(dns.Msg)
+		query.SetQuestion(d.domain, dns.TypeTXT)
+		query.RecursionDesired = true
+		if d.dnssec {
+			// The resolver does the validation, and tells us about it with the AD bit
+			query.SetEdns0(4096, true)
+			query.AuthenticatedData = true
+		}
+		response, err = exchangeSigned(ctx, query, d.nameserver, d.tsig)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if d.tsig != nil && response.Rcode == dns.RcodeNotAuth {
+		return nil, fmt.Errorf("%w: %s rejected TSIG key %s", ErrBadTSIG, d.nameserver, d.tsig.Name)
+	}
+
+	if d.dnssec && !response.AuthenticatedData {
+		return nil, fmt.Errorf("%w: response for %s from %s", ErrNotAuthenticated, d.domain, d.nameserver)
+	}
+
+	switch response.Rcode {
+	case dns.RcodeSuccess:
+		// okay
+
+	case dns.RcodeNameError: // a.k.a. NXDOMAIN
+		// TODO: add an option to allow ignoring this
+		// This is the default for safety reasons
+		return nil, fmt.Errorf("%w for domain %s", ErrNoRecords, d.domain)
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrServerFailure, dns.RcodeToString[response.Rcode])
+	}
+	return response, nil
+}
+
+func exchange(ctx context.Context, query *dns.Msg, nameserver string) (*dns.Msg, error) {
+	return exchangeSigned(ctx, query, nameserver, nil)
+}
+
+// exchangeSigned signs the query with the TSIG key (if there is one), and checks that the response is signed too
+func exchangeSigned(ctx context.Context, query *dns.Msg, nameserver string, key *TSIGKey) (*dns.Msg, error) {
+	client := new(dns.Client)
+	if key != nil {
+		secrets, err := key.sign(query)
+		if err != nil {
+			return nil, err
+		}
+		client.TsigSecret = secrets
+	}
+
+	// Always use TCP since some of our values will otherwise get truncated,
+	// and this is simpler than trying UDP and falling back. We are not using this in
+	// a performant sensitive context.
+	client.Net = "tcp"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%w executing DNS query: %w", ErrNetwork, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		client.Timeout = time.Until(deadline)
+	}
+
+	traceQuery(ctx, query, nameserver, "TCP")
+	response, _, err := client.Exchange(query, nameserver)
+	if err != nil && response != nil && key != nil {
+		// The response arrived, but its TSIG record didn't verify
+		tracef(ctx, "bad TSIG record in response from %s: %s", nameserver, err.Error())
+		return nil, fmt.Errorf("%w: response from %s: %w", ErrBadTSIG, nameserver, err)
+	}
+	if err != nil {
+		tracef(ctx, "no response from %s: %s", nameserver, err.Error())
+		return nil, fmt.Errorf("%w executing DNS query: %w", ErrNetwork, err)
+	}
+	traceResponse(ctx, response, nameserver)
+	if key != nil && response.IsTsig() == nil && response.Rcode != dns.RcodeNotAuth {
+		return nil, fmt.Errorf("%w: response from %s isn't signed", ErrBadTSIG, nameserver)
+	}
+	return response, nil
+}
+
+func txtAnswers(response *dns.Msg) ([]string, error) {
+	var results []string
+	for _, answer := range response.Answer {
+		if txt, ok := answer.(*dns.TXT); ok {
+			quotedRecord := strings.Join(txt.Txt, "")
+			unquoted, err := UnquoteTxt(quotedRecord)
+			if err != nil {
+				return nil, fmt.Errorf("error trying to unquote TXT record \"%s\": %w", quotedRecord, err)
+			}
+			results = append(results, unquoted)
+		}
+	}
+	return results, nil
+}
+
+func canonicalNameserver(options *Options, nameserver string) (string, error) {
+	if nameserver == "" {
+		if options.Nameserver == "" {
+			return configFromResolvConf(options)
+		}
+		nameserver = options.Nameserver
+	}
+	return addNameserverPort(options, nameserver)
+}
+
+func configFromResolvConf(options *Options) (string, error) {
+	resolvconf, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return "", fmt.Errorf("error opening /etc/resolv.conf: %w", err)
+	}
+	defer resolvconf.Close()
+	return readResolvConf(options, resolvconf)
+}
+
+func readResolvConf(options *Options, resolvconf io.Reader) (string, error) {
+	config, err := dns.ClientConfigFromReader(resolvconf)
+	if err != nil {
+		return "", fmt.Errorf("error reading resolv.conf DNS configuration: %w", err)
+	}
+	return config.Servers[0] + ":" + config.Port, nil
+}
+
+// Users can specify the nameserver host and port, or just the host, or neither.
+// In the last case, we fall back to the system config.  Otherwise we need to make sure we have a port (53 as default).
+func addNameserverPort(options *Options, nameserver string) (string, error) {
+	// Addresses with ports:
+	//
+	// example.com:53
+	// localhost:53
+	// 127.0.0.1:53
+	// [::1]:53
+	//
+	// Addresses without ports:
+	//
+	// example.com
+	// localhost
+	// 127.0.0.1
+	// ::1
+	// [::1]
+	//
+	// IPv6's reuse of colons makes life more complicated.  IPv6 addresses without square brackets shouldn't appear in
+	// URIs, but we need to handle them because they might come in through the --nameserver option.
+	hasPortRegex := "^([^:]+|\\[.+\\]):[0-9]+$"
+	hasPort, err := regexp.MatchString(hasPortRegex, nameserver)
+	if err != nil {
+		return "", fmt.Errorf("error reading nameserver address: %w", err)
+	}
+	if !hasPort {
+		unwrappedIPv6Regex := "^[0-9a-fA-F:]*:[0-9a-fA-F:]+$"
+		needsWrapping, err := regexp.MatchString(unwrappedIPv6Regex, nameserver)
+		if err != nil {
+			return "", fmt.Errorf("error reading nameserver address: %w", err)
+		}
+		if needsWrapping {
+			nameserver = fmt.Sprintf("[%s]:53", nameserver)
+		} else {
+			nameserver = nameserver + ":53"
+		}
+	}
+	return nameserver, nil
+}
+
+// QuoteTxt quotes a TXT string the way dig and zone files do (RFC 1035): '"' and '\\' are escaped with a backslash,
+// and other bytes outside printable ASCII are "\DDD" (the byte value in decimal).  File sources read it back.
+func QuoteTxt(record string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for _, c := range []byte(record) {
+		switch {
+		case c == '"' || c == '\\':
+			builder.WriteByte('\\')
+			builder.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&builder, "\\%03d", c)
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+var miekgEscapedEntity = regexp.MustCompile(`\\(\\|"|[0-9]{3}|.|$)`)
+
+// UnquoteTxt undoes the quoting done in unpackTxtString() in github.com/miekg/dns
+// For some reason, this library applies a custom quoting scheme to all TXT records.  It's incompatible with the quoting
+// scheme used for Go string literals, so we need a custom unquoting function.
+//
+//	" => \"
+//	\ => \\
+//	characters with byte value below 32 and above 127 => \DDD (three-digit decimal representation of byte value)
+//
+// This function should only fail if there's a bug caused by the original library changing its quoting scheme or something.
+func UnquoteTxt(quoted string) (string, error) {
+	var err error
+	unquoter := func(s string) string {
+		// s should be `\\` or `\"` or something like `\012`
+		s = s[1:len(s)] // Strip leading /
+
+		if s == `"` || s == `\` {
+			return s
+		}
+
+		if len(s) == 3 {
+			val, atoiErr := strconv.Atoi(s)
+			if atoiErr != nil {
+				err = fmt.Errorf("invalid miekg/dns escaped byte value: \\%s (this is a bug): %w", s, atoiErr)
+				return ""
+			}
+			if val < 0 || (val >= 32 && val <= 127) || val > 255 {
+				err = fmt.Errorf("miekg/dns escaped byte value out of range: \\%s (this is a bug)", s)
+				return ""
+			}
+			return string([]byte{byte(val)})
+		}
+
+		err = fmt.Errorf("invalid miekg/dns escape sequence: \\%s (this is a bug)", s)
+		return ""
+	}
+	unquoted := miekgEscapedEntity.ReplaceAllStringFunc(quoted, unquoter)
+	return unquoted, err
+}
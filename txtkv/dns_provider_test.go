@@ -1,4 +1,4 @@
-package main
+package txtkv
 
 import (
 	"errors"
@@ -26,7 +26,7 @@ func TestAddNameserverPort(t *testing.T) {
 		{"2606:2800:220:1:248:1893:25c8:1946", "[2606:2800:220:1:248:1893:25c8:1946]:53", nil},
 		{"[2606:2800:220:1:248:1893:25c8:1946]:1053", "[2606:2800:220:1:248:1893:25c8:1946]:1053", nil},
 	} {
-		options := makeDefaultOptions()
+		options := &Options{}
 		nameserver, err := addNameserverPort(options, testPair.Input)
 		if err != nil {
 			t.Error("Error", err.Error(), "for", testPair)
@@ -37,8 +37,30 @@ func TestAddNameserverPort(t *testing.T) {
 	}
 }
 
+type qualifyDomainTestPair struct {
+	Domain     string
+	BaseDomain string
+	Result     string
+}
+
+func TestQualifyDomain(t *testing.T) {
+	for _, testPair := range []qualifyDomainTestPair{
+		{"foo.example.com", "", "foo.example.com."},
+		{"foo.example.com.", "", "foo.example.com."},
+		{"app", "prod.example.com", "app.prod.example.com."},
+		{"app", "prod.example.com.", "app.prod.example.com."},
+		{"app.eu", ".prod.example.com", "app.eu.prod.example.com."},
+		{"app.example.com.", "prod.example.com", "app.example.com."},
+	} {
+		result := qualifyDomain(testPair.Domain, testPair.BaseDomain)
+		if result != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
+
 func TestReadResolvConf(t *testing.T) {
-	options := &options{}
+	options := &Options{}
 	nameserver, err := readResolvConf(options, resolvConf)
 	if err != nil {
 		t.Error("Error", err.Error())
@@ -48,14 +70,31 @@ func TestReadResolvConf(t *testing.T) {
 	}
 }
 
-type miekgUnquoteTxtTestPair struct {
+type unquoteTxtTestPair struct {
 	Input  string
 	Result string
 	Err    error
 }
 
-func TestMiekgUnquoteTxt(t *testing.T) {
-	for _, testPair := range []miekgUnquoteTxtTestPair{
+func TestQuoteTxt(t *testing.T) {
+	for _, testPair := range []unquoteTxtTestPair{
+		{"foo=bar", `"foo=bar"`, nil},
+		{`say "hi" \ bye`, `"say \"hi\" \\ bye"`, nil},
+		{"cert=-----BEGIN\n\tcafé", `"cert=-----BEGIN\010\009caf\195\169"`, nil},
+		{"\x00\x7f\xff", `"\000\127\255"`, nil},
+	} {
+		quoted := QuoteTxt(testPair.Input)
+		if quoted != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", quoted, "for", testPair)
+		}
+		if unquoted, tail, err := unquoteString(quoted[1:]); err != nil || unquoted != testPair.Input || tail != "" {
+			t.Error("Expected round trip but got", unquoted, tail, err, "for", testPair)
+		}
+	}
+}
+
+func TestUnquoteTxt(t *testing.T) {
+	for _, testPair := range []unquoteTxtTestPair{
 		{"", "", nil},
 		{"foo", "foo", nil},
 		{`\\`, `\`, nil},
@@ -69,7 +108,7 @@ func TestMiekgUnquoteTxt(t *testing.T) {
 		{`\"\`, "", errors.New("Invalid escape sequence")},
 		{`\\\`, "", errors.New("Invalid escape sequence")},
 	} {
-		result, err := miekgUnquoteTxt(testPair.Input)
+		result, err := UnquoteTxt(testPair.Input)
 
 		if err != nil && testPair.Err == nil {
 			t.Error("Unexpected error", err.Error(), "for", testPair)
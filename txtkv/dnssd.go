@@ -0,0 +1,200 @@
+package txtkv
+
+// DNS-Based Service Discovery (https://tools.ietf.org/html/rfc6763)
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// ServiceInstance is a DNS-SD service instance, found through its SRV and TXT records.
+type ServiceInstance struct {
+	// Name is the full domain name of the instance, like "Printer._ipp._tcp.example.com.".
+	Name string
+	// Instance is the unescaped instance part of the name, like "Printer".
+	Instance string
+	// Targets are the SRV records of the instance, sorted by priority (and then by weight, highest first).
+	Targets []ServiceTarget
+	// Records are the TXT strings of the instance.  Unlike TXT records in general, each string of a DNS-SD TXT record
+	// is a separate attribute.
+	Records []string
+	// Table has the TXT attributes parsed into key/value pairs, using SyntaxRFC6763 unless Options.Syntax says
+	// otherwise.
+	Table *Table
+}
+
+// ServiceTarget is the host and port from an SRV record.
+type ServiceTarget struct {
+	Host     string
+	Port     uint16
+	Priority uint16
+	Weight   uint16
+}
+
+// Browse finds the instances of a DNS-SD service type (like "_http._tcp.example.com") by following its PTR records,
+// and resolves each instance.  Services under ".local" are browsed with multicast DNS.  Other names without a trailing
+// dot are relative to Options.BaseDomain.
+func (c *Client) Browse(ctx context.Context, service string) ([]*ServiceInstance, error) {
+	ctx = c.traceContext(ctx)
+	service = c.qualifyServiceName(service)
+	resolve, err := c.serviceResolveFunc(service)
+	if err != nil {
+		return nil, err
+	}
+	response, err := resolve(ctx, service, dns.TypePTR)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up PTR records for %s: %w", service, err)
+	}
+	if response.Rcode == dns.RcodeNameError {
+		return nil, fmt.Errorf("%w: no such service %s", ErrNoRecords, service)
+	}
+
+	var names []string
+	for _, answer := range response.Answer {
+		if ptr, ok := answer.(*dns.PTR); ok && strings.EqualFold(ptr.Hdr.Name, service) {
+			names = append(names, ptr.Ptr)
+		}
+	}
+	sort.Strings(names)
+
+	instances := []*ServiceInstance{}
+	for _, name := range names {
+		instance, err := resolveInstance(ctx, resolve, name, c.instanceSyntax())
+		if err != nil {
+			return nil, err
+		}
+		if err := c.verify(instance.Table, name, name); err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// ResolveInstance looks up the SRV and TXT records of a DNS-SD service instance name (like
+// "Printer._ipp._tcp.example.com"), relative to Options.BaseDomain like the service types of Browse.
+func (c *Client) ResolveInstance(ctx context.Context, name string) (*ServiceInstance, error) {
+	ctx = c.traceContext(ctx)
+	name = c.qualifyServiceName(name)
+	resolve, err := c.serviceResolveFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := resolveInstance(ctx, resolve, name, c.instanceSyntax())
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verify(instance.Table, name, name); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// Multicast names are always under ".local", whatever the base domain
+func (c *Client) qualifyServiceName(name string) string {
+	if isMulticastName(name) {
+		return dns.Fqdn(name)
+	}
+	return qualifyDomain(name, c.options.BaseDomain)
+}
+
+func (c *Client) serviceResolveFunc(name string) (resolveFunc, error) {
+	if isMulticastName(name) {
+		return makeMulticastResolver(&c.options).resolve, nil
+	}
+	return makeResolveFunc(&c.options)
+}
+
+func (c *Client) instanceSyntax() string {
+	if c.options.Syntax == "" {
+		return SyntaxRFC6763
+	}
+	return c.options.Syntax
+}
+
+func resolveInstance(ctx context.Context, resolve resolveFunc, name string, syntax string) (*ServiceInstance, error) {
+	instance := &ServiceInstance{
+		Name:     name,
+		Instance: unescapeLabel(dns.SplitDomainName(name)[0]),
+	}
+
+	response, err := resolve(ctx, name, dns.TypeSRV)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up SRV records for %s: %w", name, err)
+	}
+	for _, answer := range response.Answer {
+		if srv, ok := answer.(*dns.SRV); ok {
+			instance.Targets = append(instance.Targets, ServiceTarget{
+				Host:     srv.Target,
+				Port:     srv.Port,
+				Priority: srv.Priority,
+				Weight:   srv.Weight,
+			})
+		}
+	}
+	if len(instance.Targets) == 0 {
+		return nil, fmt.Errorf("%w: no SRV records for service instance %s", ErrNoRecords, name)
+	}
+	sort.SliceStable(instance.Targets, func(i, j int) bool {
+		a, b := instance.Targets[i], instance.Targets[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return a.Weight > b.Weight
+	})
+
+	response, err = resolve(ctx, name, dns.TypeTXT)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up TXT records for %s: %w", name, err)
+	}
+	instance.Records, err = txtStrings(response)
+	if err != nil {
+		return nil, err
+	}
+	instance.Table = parseRecordsFrom(instance.Records, strings.TrimSuffix(name, "."), syntax)
+	return instance, nil
+}
+
+// Like txtAnswers, but without joining the strings of each TXT record
+func txtStrings(response *dns.Msg) ([]string, error) {
+	var results []string
+	for _, answer := range response.Answer {
+		if txt, ok := answer.(*dns.TXT); ok {
+			for _, quoted := range txt.Txt {
+				unquoted, err := UnquoteTxt(quoted)
+				if err != nil {
+					return nil, fmt.Errorf("error trying to unquote TXT string \"%s\": %w", quoted, err)
+				}
+				results = append(results, unquoted)
+			}
+		}
+	}
+	return results, nil
+}
+
+// Labels in names from miekg/dns have special characters escaped as \X or \DDD
+func unescapeLabel(label string) string {
+	var result []byte
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		if c != '\\' || i+1 >= len(label) {
+			result = append(result, c)
+			continue
+		}
+		if i+3 < len(label) {
+			if val, err := strconv.Atoi(label[i+1 : i+4]); err == nil && val <= 255 {
+				result = append(result, byte(val))
+				i += 3
+				continue
+			}
+		}
+		result = append(result, label[i+1])
+		i++
+	}
+	return string(result)
+}
@@ -0,0 +1,97 @@
+package txtkv
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBrowse(t *testing.T) {
+	address := startTestServer(t, "127.0.0.1:0", []string{
+		`_http._tcp.example.com. 300 IN PTR Web\ Server._http._tcp.example.com.`,
+		`_http._tcp.example.com. 300 IN PTR api._http._tcp.example.com.`,
+		`api._http._tcp.example.com. 300 IN SRV 10 5 8080 api2.example.com.`,
+		`api._http._tcp.example.com. 300 IN SRV 0 5 8080 api1.example.com.`,
+		`api._http._tcp.example.com. 300 IN SRV 10 50 8080 api3.example.com.`,
+		`api._http._tcp.example.com. 300 IN TXT "path=/v1" "txtvers=1"`,
+		`Web\ Server._http._tcp.example.com. 300 IN SRV 0 0 80 www.example.com.`,
+		`Web\ Server._http._tcp.example.com. 300 IN TXT "path=/"`,
+		`_ipp._tcp.example.com. 300 IN PTR broken._ipp._tcp.example.com.`,
+	})
+	client := NewClient(Options{Nameserver: address})
+
+	instances, err := client.Browse(context.Background(), "_http._tcp.example.com")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if len(instances) != 2 {
+		t.Fatal("Expected 2 instances but got", len(instances))
+	}
+
+	if instances[0].Instance != "Web Server" {
+		t.Error("Expected \"Web Server\" but got", instances[0].Instance)
+	}
+	api := instances[1]
+	if api.Name != "api._http._tcp.example.com." {
+		t.Error("Expected api._http._tcp.example.com. but got", api.Name)
+	}
+	var hosts []string
+	for _, target := range api.Targets {
+		hosts = append(hosts, target.Host)
+	}
+	if expected := []string{"api1.example.com.", "api3.example.com.", "api2.example.com."}; !reflect.DeepEqual(hosts, expected) {
+		t.Error("Expected", expected, "but got", hosts)
+	}
+	if expected := []string{"path=/v1", "txtvers=1"}; !reflect.DeepEqual(api.Records, expected) {
+		t.Error("Expected", expected, "but got", api.Records)
+	}
+	if value, err := api.Table.Lookup("txtvers"); err != nil || value != "1" {
+		t.Error("Expected 1 but got", value, err)
+	}
+
+	if _, err := client.Browse(context.Background(), "_ipp._tcp.example.com"); !errors.Is(err, ErrNoRecords) {
+		t.Error("Expected ErrNoRecords for instance without SRV records but got", err)
+	}
+	if _, err := client.Browse(context.Background(), "_ftp._tcp.example.com"); !errors.Is(err, ErrNoRecords) {
+		t.Error("Expected ErrNoRecords for missing service but got", err)
+	}
+
+	instance, err := client.ResolveInstance(context.Background(), "api._http._tcp.example.com")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if !reflect.DeepEqual(instance, api) {
+		t.Error("Expected", api, "but got", instance)
+	}
+
+	client = NewClient(Options{Nameserver: address, BaseDomain: "example.com"})
+	instances, err = client.Browse(context.Background(), "_http._tcp")
+	if err != nil || len(instances) != 2 {
+		t.Error("Expected 2 instances relative to the base domain but got", instances, err)
+	}
+	instance, err = client.ResolveInstance(context.Background(), "api._http._tcp")
+	if err != nil || !reflect.DeepEqual(instance, api) {
+		t.Error("Expected", api, "relative to the base domain but got", instance, err)
+	}
+}
+
+type unescapeLabelTestPair struct {
+	Label  string
+	Result string
+}
+
+func TestUnescapeLabel(t *testing.T) {
+	for _, testPair := range []unescapeLabelTestPair{
+		{"printer", "printer"},
+		{`Web\032Server`, "Web Server"},
+		{`a\.b`, "a.b"},
+		{`back\\slash`, `back\slash`},
+		{`caf\195\169`, "café"},
+		{`trailing\`, `trailing\`},
+	} {
+		if result := unescapeLabel(testPair.Label); result != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package txtkv
+
+// crypto/hkdf needs Go 1.24 or later
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EncryptedPrefix starts values that are encrypted to a recipient's X25519 key.  The rest of the value is base64 data:
+// an ephemeral X25519 public key, then an AES-256-GCM nonce and ciphertext.  The AES key is derived from the shared
+// secret with HKDF-SHA256, and the (lower case) key name is authenticated, so encrypted values can't be moved to
+// other keys.
+const EncryptedPrefix = "enc:v1:"
+
+const (
+	encryptionInfo = "sdget enc:v1"
+	x25519KeySize  = 32
+)
+
+// ParseDecryptionKeys parses all the X25519 private keys in PEM-encoded PKCS #8 form ("PRIVATE KEY", like from
+// "openssl genpkey -algorithm x25519").
+func ParseDecryptionKeys(data []byte) ([]*ecdh.PrivateKey, error) {
+	var keys []*ecdh.PrivateKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PRIVATE KEY" {
+			continue
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing private key: %w", err)
+		}
+		privateKey, ok := key.(*ecdh.PrivateKey)
+		if !ok || privateKey.Curve() != ecdh.X25519() {
+			return nil, fmt.Errorf("unsupported private key type %T: only X25519 is supported", key)
+		}
+		keys = append(keys, privateKey)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no PEM-encoded private keys found")
+	}
+	return keys, nil
+}
+
+// ParseRecipientKey parses an X25519 public key in PEM form ("PUBLIC KEY", like from "openssl pkey -pubout").
+func ParseRecipientKey(data []byte) (*ecdh.PublicKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no PEM-encoded public key found")
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing public key: %w", err)
+		}
+		publicKey, ok := key.(*ecdh.PublicKey)
+		if !ok || publicKey.Curve() != ecdh.X25519() {
+			return nil, fmt.Errorf("unsupported public key type %T: only X25519 is supported", key)
+		}
+		return publicKey, nil
+	}
+}
+
+// EncryptValue encrypts the value of a key to a recipient's public key.
+func EncryptValue(recipient *ecdh.PublicKey, key string, value string) (string, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("error generating ephemeral key: %w", err)
+	}
+	aead, err := makeAEAD(ephemeral, recipient, ephemeral.PublicKey())
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %w", err)
+	}
+	data := append(ephemeral.PublicKey().Bytes(), nonce...)
+	data = aead.Seal(data, nonce, []byte(value), []byte(strings.ToLower(key)))
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecryptValue decrypts the value of a key with any of the given private keys.  Values without EncryptedPrefix are
+// returned unchanged.  Without any keys, encrypted values are errors matching ErrNoDecryptionKey, and other errors
+// match ErrDecryption.
+func DecryptValue(keys []*ecdh.PrivateKey, key string, value string) (string, error) {
+	if !strings.HasPrefix(value, EncryptedPrefix) {
+		return value, nil
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("%w: value of key %s is encrypted", ErrNoDecryptionKey, key)
+	}
+	data, err := base64.StdEncoding.DecodeString(value[len(EncryptedPrefix):])
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid base64 data in value of key %s", ErrDecryption, key)
+	}
+	if len(data) < x25519KeySize {
+		return "", fmt.Errorf("%w: value of key %s is too short", ErrDecryption, key)
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(data[:x25519KeySize])
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid ephemeral key in value of key %s", ErrDecryption, key)
+	}
+	data = data[x25519KeySize:]
+
+	for _, privateKey := range keys {
+		aead, err := makeAEAD(privateKey, ephemeral, ephemeral)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrDecryption, err.Error())
+		}
+		if len(data) < aead.NonceSize() {
+			return "", fmt.Errorf("%w: value of key %s is too short", ErrDecryption, key)
+		}
+		plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(strings.ToLower(key)))
+		if err == nil {
+			return string(plaintext), nil
+		}
+	}
+	return "", fmt.Errorf("%w: value of key %s can't be decrypted with any of the given keys", ErrDecryption, key)
+}
+
+// Both sides derive the same AES key from the shared secret, salted with the ephemeral public key
+func makeAEAD(privateKey *ecdh.PrivateKey, publicKey *ecdh.PublicKey, ephemeral *ecdh.PublicKey) (cipher.AEAD, error) {
+	secret, err := privateKey.ECDH(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("error computing shared secret: %w", err)
+	}
+	aesKey, err := hkdf.Key(sha256.New, secret, ephemeral.Bytes(), encryptionInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
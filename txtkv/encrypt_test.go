@@ -0,0 +1,88 @@
+package txtkv
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func generateTestDecryptionKey(t *testing.T) *ecdh.PrivateKey {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	return key
+}
+
+func TestParseEncryptionKeys(t *testing.T) {
+	privateKey := generateTestDecryptionKey(t)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	keys, err := ParseDecryptionKeys(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil || len(keys) != 1 || !keys[0].Equal(privateKey) {
+		t.Error("Expected 1 private key but got", keys, err)
+	}
+
+	der, err = x509.MarshalPKIXPublicKey(privateKey.PublicKey())
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	publicKey, err := ParseRecipientKey(publicPEM)
+	if err != nil || !publicKey.Equal(privateKey.PublicKey()) {
+		t.Error("Expected public key but got", publicKey, err)
+	}
+
+	if _, err := ParseDecryptionKeys(publicPEM); err == nil {
+		t.Error("Expected error for data without private keys")
+	}
+	if _, err := ParseRecipientKey([]byte("not PEM")); err == nil {
+		t.Error("Expected error for data without a public key")
+	}
+}
+
+func TestEncryptValue(t *testing.T) {
+	key := generateTestDecryptionKey(t)
+	otherKey := generateTestDecryptionKey(t)
+
+	encrypted, err := EncryptValue(key.PublicKey(), "API-Token", "s3cret")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if !strings.HasPrefix(encrypted, EncryptedPrefix) || strings.Contains(encrypted, "s3cret") {
+		t.Error("Expected encrypted value but got", encrypted)
+	}
+
+	if value, err := DecryptValue([]*ecdh.PrivateKey{otherKey, key}, "api-token", encrypted); err != nil || value != "s3cret" {
+		t.Error("Expected s3cret but got", value, err)
+	}
+	if value, err := DecryptValue([]*ecdh.PrivateKey{key}, "plain", "not encrypted"); err != nil || value != "not encrypted" {
+		t.Error("Expected value to be unchanged but got", value, err)
+	}
+
+	for _, testCase := range []struct {
+		Keys  []*ecdh.PrivateKey
+		Key   string
+		Value string
+	}{
+		{[]*ecdh.PrivateKey{otherKey}, "api-token", encrypted},
+		{[]*ecdh.PrivateKey{key}, "other-key", encrypted},
+		{[]*ecdh.PrivateKey{key}, "api-token", encrypted[:len(encrypted)-8]},
+		{[]*ecdh.PrivateKey{key}, "api-token", EncryptedPrefix + "not base64!"},
+		{[]*ecdh.PrivateKey{key}, "api-token", EncryptedPrefix + "c2hvcnQ="},
+	} {
+		if _, err := DecryptValue(testCase.Keys, testCase.Key, testCase.Value); !errors.Is(err, ErrDecryption) {
+			t.Error("Expected ErrDecryption but got", err, "for", testCase)
+		}
+	}
+
+	if _, err := DecryptValue(nil, "api-token", encrypted); !errors.Is(err, ErrNoDecryptionKey) {
+		t.Error("Expected ErrNoDecryptionKey without keys but got", err)
+	}
+}
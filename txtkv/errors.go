@@ -0,0 +1,142 @@
+package txtkv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedScheme is returned for source URIs with a scheme that has no provider.
+	ErrUnsupportedScheme = errors.New("unsupported URI scheme")
+
+	// ErrNoRecords is returned when a DNS domain doesn't exist (NXDOMAIN).  This is an error, rather than an empty
+	// record set, for safety reasons.
+	ErrNoRecords = errors.New("no TXT records")
+
+	// ErrNetwork is returned when a DNS query can't be made or doesn't get a response (like timeouts or refused
+	// connections).  Retrying might help.
+	ErrNetwork = errors.New("network error")
+
+	// ErrServerFailure is returned when a DNS server responds with an error code other than NXDOMAIN (like SERVFAIL or
+	// REFUSED).
+	ErrServerFailure = errors.New("error from remote DNS server")
+
+	// ErrNotAuthenticated is returned when a response should have been authenticated with DNSSEC, but wasn't.
+	ErrNotAuthenticated = errors.New("not authenticated with DNSSEC")
+
+	// ErrBadTSIG is returned when a nameserver rejects the TSIG key of a query, or its response isn't signed with it.
+	ErrBadTSIG = errors.New("TSIG verification failed")
+
+	// ErrFileAccess is returned when a file source can't be opened or read.
+	ErrFileAccess = errors.New("error reading file")
+
+	// ErrStaleSnapshot is returned when the records of a snapshot source are older than its maximum age.
+	ErrStaleSnapshot = errors.New("snapshot is too old")
+
+	// ErrKeyNotFound is returned when a key that's expected to have one value has none.
+	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrTooManyValues is returned when a key that's expected to have one value has more than one.
+	ErrTooManyValues = errors.New("too many values for key")
+
+	// ErrIncludeCycle is returned when a source includes itself, directly or indirectly.
+	ErrIncludeCycle = errors.New("include cycle")
+
+	// ErrIncludeDepth is returned when include directives are nested too deeply.
+	ErrIncludeDepth = errors.New("includes nested too deeply")
+
+	// ErrInvalidChunks is returned when the chunks of a chunked value are missing, repeated or inconsistent.
+	ErrInvalidChunks = errors.New("invalid chunked value")
+
+	// ErrBadSignature is returned when a source doesn't have a valid signature from a trusted key.
+	ErrBadSignature = errors.New("signature verification failed")
+
+	// ErrDecryption is returned when an encrypted value can't be decrypted.
+	ErrDecryption = errors.New("decryption failed")
+
+	// ErrNoDecryptionKey is returned when a value is encrypted, but there are no keys to decrypt it with.
+	ErrNoDecryptionKey = errors.New("no decryption key")
+
+	// ErrLocalReference is returned when the records of a network source (like DNS) reference a local source (like a
+	// file), which could leak its contents to whoever publishes them.
+	ErrLocalReference = errors.New("network source can't reference a local source")
+
+	// ErrInterpolationCycle is returned when a value references itself, directly or indirectly.
+	ErrInterpolationCycle = errors.New("interpolation cycle")
+)
+
+// SourceError is returned when a source can't be parsed, or a provider can't be set up for it.
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("invalid source \"%s\": %s", e.Source, e.Err.Error())
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// FetchError is returned when the TXT records of a source can't be fetched.  Err says why, and matches one of
+// ErrNoRecords, ErrNetwork, ErrServerFailure, ErrNotAuthenticated, ErrBadTSIG or ErrFileAccess where it can.
+type FetchError struct {
+	Source string
+	Err    error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("error fetching TXT records from %s: %s", e.Source, e.Err.Error())
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// KeyError is returned when a key doesn't have exactly one value.  Err is ErrKeyNotFound or ErrTooManyValues.
+type KeyError struct {
+	Key   string
+	Count int
+	Err   error
+}
+
+func (e *KeyError) Error() string {
+	if e.Err == ErrTooManyValues {
+		return fmt.Sprintf("%d values found for key %s, but only 1 was expected", e.Count, e.Key)
+	}
+	return fmt.Sprintf("no values found for key %s", e.Key)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// ValueError is returned when a value can't be parsed as the requested type.
+type ValueError struct {
+	Key   string
+	Value string
+	Type  string
+	Err   error
+}
+
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("value \"%s\" of key %s is not a valid %s: %s", e.Value, e.Key, e.Type, e.Err.Error())
+}
+
+func (e *ValueError) Unwrap() error {
+	return e.Err
+}
+
+// SchemaError is returned when a table doesn't match a schema.  Problems describes each mismatch.
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	if len(e.Problems) == 1 {
+		return "schema violation: " + e.Problems[0]
+	}
+	return fmt.Sprintf("%d schema violations: %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
@@ -0,0 +1,122 @@
+package txtkv
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+const defaultMaxConcurrentFetches = 8
+
+// Fetches of the same source that overlap share one provider query and its result, like the singleflight helper in
+// the dns package.  With Options.ReuseFetches, finished fetches are kept, so later fetches share their result too.
+// Tables are never modified after parsing, so sharing them is safe.
+type fetchGroup struct {
+	mutex sync.Mutex
+	calls map[string]*fetchCall
+	keep  bool
+}
+
+type fetchCall struct {
+	done  chan struct{}
+	table *Table
+	err   error
+}
+
+// do runs fetch for a source, unless a fetch with the same key is already running (or finished, if they're kept)
+func (g *fetchGroup) do(ctx context.Context, key string, source string, fetch func() (*Table, error)) (*Table, error) {
+	g.mutex.Lock()
+	call, found := g.calls[key]
+	if !found {
+		call = &fetchCall{done: make(chan struct{})}
+		g.calls[key] = call
+	}
+	g.mutex.Unlock()
+
+	if found {
+		select {
+		case <-call.done:
+			tracef(ctx, "reusing the earlier fetch of %s", source)
+			return call.table, call.err
+		default:
+		}
+		tracef(ctx, "waiting for the fetch of %s already in progress", source)
+		select {
+		case <-call.done:
+			return call.table, call.err
+		case <-ctx.Done():
+			return nil, &FetchError{Source: source, Err: ctx.Err()}
+		}
+	}
+
+	call.table, call.err = fetch()
+	if !g.keep {
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+	}
+	close(call.done)
+	return call.table, call.err
+}
+
+// Providers that can tell when differently written sources are the same (like "foo.example.com" and
+// "dns:FOO.example.com.") have a fetch key, so that fetches of them are shared.  Other sources are only the same if
+// they're written the same way.
+type keyedProvider interface {
+	fetchKey() string
+}
+
+func fetchKey(source string, provider Provider) string {
+	if keyed, ok := provider.(keyedProvider); ok {
+		return keyed.fetchKey()
+	}
+	return source
+}
+
+// acquire waits for one of the Options.MaxConcurrentFetches slots for querying a provider
+func (c *Client) acquire(ctx context.Context) error {
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) release() {
+	<-c.slots
+}
+
+// fetchResult is the outcome of fetching one of several sources
+type fetchResult struct {
+	table *Table
+	err   error
+}
+
+// fetchAll runs fetch on all the sources at once, and returns the results in the same order as the sources.  The
+// number of provider queries running at the same time is still limited by Options.MaxConcurrentFetches.
+func fetchAll(ctx context.Context, sources []string, fetch func(context.Context, string) (*Table, error)) []fetchResult {
+	results := make([]fetchResult, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source string) {
+			defer wg.Done()
+			results[i].table, results[i].err = fetch(ctx, source)
+		}(i, source)
+	}
+	wg.Wait()
+	return results
+}
+
+// Trace lines from concurrent fetches mustn't be mixed up
+type syncWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.writer.Write(p)
+}
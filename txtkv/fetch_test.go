@@ -0,0 +1,157 @@
+package txtkv
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Counts the lines of a trace, to see when fetches have started waiting
+type traceCounter struct {
+	mutex sync.Mutex
+	lines map[string]int
+}
+
+func (c *traceCounter) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.lines[strings.TrimSpace(string(p))]++
+	return len(p), nil
+}
+
+func (c *traceCounter) count(line string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lines[line]
+}
+
+func TestFetchGroup(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		group := &fetchGroup{calls: make(map[string]*fetchCall), keep: keep}
+		trace := &traceCounter{lines: make(map[string]int)}
+		ctx := context.WithValue(context.Background(), traceKey{}, io.Writer(trace))
+		release := make(chan struct{})
+		var mutex sync.Mutex
+		fetches := 0
+		fetch := func() (*Table, error) {
+			mutex.Lock()
+			fetches++
+			mutex.Unlock()
+			<-release
+			return ParseRecords([]string{"foo=bar"}), nil
+		}
+
+		const callers = 5
+		tables := make([]*Table, callers)
+		var wg sync.WaitGroup
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				tables[i], _ = group.do(ctx, "test:foo", "foo", fetch)
+			}(i)
+		}
+		// Wait until every caller but the one fetching is waiting for the fetch
+		waiting := tracePrefix + "waiting for the fetch of foo already in progress"
+		for deadline := time.Now().Add(5 * time.Second); trace.count(waiting) < callers-1; {
+			if time.Now().After(deadline) {
+				t.Fatal("Timed out waiting for fetches to start")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+		wg.Wait()
+
+		if fetches != 1 {
+			t.Error("Expected 1 fetch but got", fetches)
+		}
+		for _, table := range tables {
+			if table != tables[0] {
+				t.Error("Expected all callers to get the same table")
+			}
+		}
+
+		// Finished fetches are only reused if they're kept
+		expected := 2
+		if keep {
+			expected = 1
+		}
+		if table, err := group.do(ctx, "test:foo", "foo", fetch); err != nil || fetches != expected || (keep && table != tables[0]) {
+			t.Error("Expected", expected, "fetches but got", fetches, err, "with keep", keep)
+		}
+	}
+}
+
+type fetchKeyTestPair struct {
+	Options Options
+	Sources []string
+	Shared  bool
+}
+
+func TestFetchKey(t *testing.T) {
+	for _, testPair := range []fetchKeyTestPair{
+		{Options{Nameserver: "127.0.0.1"}, []string{"foo.example.com", "dns:foo.example.com", "dns:FOO.example.com.", "foo.example.com."}, true},
+		{Options{Nameserver: "127.0.0.1", BaseDomain: "example.com"}, []string{"foo", "foo.example.com.", "dns:Foo"}, true},
+		{Options{Nameserver: "127.0.0.1"}, []string{"foo.example.com", "dns://127.0.0.1:53/foo.example.com"}, true},
+		{Options{Nameserver: "127.0.0.1"}, []string{"foo.example.com", "dns://10.0.0.2/foo.example.com"}, false},
+		{Options{Nameserver: "127.0.0.1"}, []string{"foo.example.com", "dns:foo.example.com?dnssec"}, false},
+		{Options{Nameserver: "127.0.0.1"}, []string{"foo.example.com", "bar.example.com"}, false},
+		{Options{}, []string{"file:///tmp/Records", "file:///tmp/records"}, false},
+	} {
+		client := NewClient(testPair.Options)
+		keys := make(map[string]bool)
+		for _, source := range testPair.Sources {
+			provider, err := client.Provider(source)
+			if err != nil {
+				t.Fatal("Error", err.Error(), "for", source)
+			}
+			keys[fetchKey(source, provider)] = true
+		}
+		if shared := len(keys) == 1; shared != testPair.Shared {
+			t.Error("Expected shared", testPair.Shared, "but got keys", keys, "for", testPair.Sources)
+		}
+	}
+}
+
+func TestFetchAll(t *testing.T) {
+	client := NewClient(Options{MaxConcurrentFetches: 2})
+	ctx := context.Background()
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	fetch := func(ctx context.Context, source string) (*Table, error) {
+		if err := client.acquire(ctx); err != nil {
+			return nil, err
+		}
+		defer client.release()
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		return ParseRecords([]string{"source=" + source}), nil
+	}
+
+	sources := []string{"a", "b", "c", "d", "e"}
+	var values []string
+	for _, result := range fetchAll(ctx, sources, fetch) {
+		if result.err != nil {
+			t.Fatal("Error", result.err.Error())
+		}
+		values = append(values, result.table.LookupList("source")...)
+	}
+	if !reflect.DeepEqual(values, sources) {
+		t.Error("Expected", sources, "but got", values)
+	}
+	if maxRunning > 2 {
+		t.Error("Expected at most 2 concurrent fetches but got", maxRunning)
+	}
+}
@@ -0,0 +1,369 @@
+package txtkv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/govau/sdget/internal/yamlnode"
+	"gopkg.in/yaml.v3"
+)
+
+// File formats, chosen with the format parameter or by the file extension.  Files without a known extension are
+// detected from their contents (see detectFileFormat).
+const (
+	fileFormatLines = "lines"
+	fileFormatJSON  = "json"
+	fileFormatYAML  = "yaml"
+	fileFormatDig   = "dig"
+	fileFormatHost  = "host"
+)
+
+var fileFormats = []string{fileFormatLines, fileFormatJSON, fileFormatYAML, fileFormatDig, fileFormatHost}
+
+type fileProvider struct {
+	options *Options
+	path    string
+	// format is empty when it should be detected
+	format string
+	// owner selects the records of one name from dig and host files
+	owner string
+	// name is the DNS name the records are published under, for checking signatures
+	name string
+}
+
+func init() {
+	RegisterScheme("file", Scheme{
+		Components: ComponentAuthority | ComponentQuery,
+		Params:     []string{"format", "owner", "name"},
+		New:        newFileProvider,
+	})
+}
+
+func newFileProvider(options *Options, uri *URI) (Provider, error) {
+	provider, err := makeFileProvider(options, uri.Authority, uri.Path)
+	if err != nil {
+		return nil, err
+	}
+	if format, ok := uri.param("format"); ok {
+		if !containsString(fileFormats, format) {
+			return nil, fmt.Errorf("invalid file format \"%s\" (%s)", format, strings.Join(fileFormats, ", "))
+		}
+		provider.format = format
+	}
+	provider.owner, _ = uri.param("owner")
+	provider.name, _ = uri.param("name")
+	if provider.name == "" {
+		// The owner of the records is where they were published
+		provider.name = provider.owner
+	}
+	return provider, nil
+}
+
+func (f *fileProvider) recordName() string {
+	return f.name
+}
+
+func makeFileProvider(options *Options, hostname string, path string) (*fileProvider, error) {
+	if hostname != "" && hostname != "localhost" {
+		machineHostname, _ := os.Hostname()
+		if hostname != machineHostname {
+			return nil, fmt.Errorf("unsupported hostname in file URI: %s", hostname)
+		}
+	}
+	return &fileProvider{
+		options: options,
+		path:    path,
+		format:  fileFormatFromPath(path),
+	}, nil
+}
+
+func fileFormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return fileFormatJSON
+	case ".yaml", ".yml":
+		return fileFormatYAML
+	default:
+		return ""
+	}
+}
+
+func (f *fileProvider) TxtRecords(ctx context.Context) ([]string, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("%w for TXT records: %w", ErrFileAccess, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w \"%s\" for TXT records: %w", ErrFileAccess, f.path, err)
+	}
+	format := f.format
+	if format == "" {
+		format = detectFileFormat(data)
+		tracef(ctx, "%s looks like a %s file", f.path, format)
+	}
+	if f.owner != "" && format != fileFormatDig && format != fileFormatHost {
+		return nil, fmt.Errorf("the owner parameter only applies to dig and host files, not %s files", format)
+	}
+
+	var records []string
+	switch format {
+	case fileFormatLines:
+		return f.getTxtRecordsFromReader(bytes.NewReader(data))
+	case fileFormatJSON:
+		records, err = parseJSONRecords(data)
+	case fileFormatYAML:
+		records, err = parseYAMLRecords(data)
+	case fileFormatDig:
+		records, err = parseZoneRecords(nsupdateToZone(data), f.owner, f.options.Syntax)
+	case fileFormatHost:
+		records, err = parseZoneRecords(hostToZone(data), f.owner, f.options.Syntax)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error in %s: %w", f.path, err)
+	}
+	tracef(ctx, "%d TXT strings from %s file %s", len(records), format, f.path)
+	return records, nil
+}
+
+func (f *fileProvider) getTxtRecordsFromReader(input io.Reader) ([]string, error) {
+	var result []string
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		record := scanner.Text()
+		unquoted, err := unquoteRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("error unquoting TXT record \"%s\": %w", record, err)
+		}
+		// The strings of a multi-string record (like "abc" "def" from dig +short) are joined, like DNS answers
+		if f.options.Syntax == SyntaxRFC6763 {
+			result = append(result, unquoted...)
+		} else {
+			result = append(result, strings.Join(unquoted, ""))
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%w \"%s\" for TXT records: %w", ErrFileAccess, f.path, err)
+	}
+	return result, nil
+}
+
+// unquoteRecord returns the strings of a line: one raw string, or one or more quoted strings separated by spaces
+func unquoteRecord(record string) ([]string, error) {
+	if record == "" || record[0] != '"' {
+		return []string{record}, nil
+	}
+	var result []string
+	for rest := record; rest != ""; {
+		if rest[0] != '"' {
+			return nil, fmt.Errorf("unexpected %q after quoted string", rest)
+		}
+		unquoted, tail, err := unquoteString(rest[1:])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, unquoted)
+		rest = strings.TrimLeft(tail, " \t")
+	}
+	return result, nil
+}
+
+// unquoteString reads a quoted string up to the closing quote, and returns the rest of the line.  Escapes are like
+// dig's output and zone files (RFC 1035): "\DDD" is a byte in decimal, and other characters after a backslash are
+// themselves.  C-style escapes (like "\n" and "\x21") also work, like in older versions.
+func unquoteString(quoted string) (string, string, error) {
+	var builder strings.Builder
+	for i := 0; i < len(quoted); {
+		switch c := quoted[i]; {
+		case c == '"':
+			return builder.String(), quoted[i+1:], nil
+
+		case c != '\\':
+			builder.WriteByte(c)
+			i++
+
+		case i+3 < len(quoted) && isDigits(quoted[i+1:i+4]):
+			value, _ := strconv.Atoi(quoted[i+1 : i+4])
+			if value > 255 {
+				return "", "", fmt.Errorf("invalid escape \"%s\": byte values must be 0 to 255", quoted[i:i+4])
+			}
+			builder.WriteByte(byte(value))
+			i += 4
+
+		case i+1 < len(quoted):
+			value, multibyte, tail, err := strconv.UnquoteChar(quoted[i:], '"')
+			if err != nil {
+				// Anything else after a backslash is just itself
+				value, multibyte, tail = rune(quoted[i+1]), false, quoted[i+2:]
+			}
+			if multibyte {
+				builder.WriteRune(value)
+			} else {
+				builder.WriteByte(byte(value))
+			}
+			i = len(quoted) - len(tail)
+
+		default:
+			i++
+		}
+	}
+	return "", "", errors.New("missing closing quote")
+}
+
+func isDigits(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
+
+// JSON files are either an array of TXT strings (like the output of "sdget dump --format json"), or an object of keys
+// to values.  Values can be strings, numbers, booleans, or arrays of them for repeated keys, and nested objects are
+// flattened into dotted keys (the reverse of --nest).
+func parseJSONRecords(data []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if decoder.More() {
+		return nil, errors.New("invalid JSON: more than one value")
+	}
+
+	switch document := document.(type) {
+	case []interface{}:
+		records := []string{}
+		for i, item := range document {
+			record, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("item %d of the array isn't a string", i+1)
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	case map[string]interface{}:
+		records := []string{}
+		return records, addJSONRecords(&records, "", document)
+	default:
+		return nil, errors.New("expected an array of TXT strings, or an object of keys and values")
+	}
+}
+
+func addJSONRecords(records *[]string, prefix string, object map[string]interface{}) error {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		key := prefix + name
+		switch value := object[name].(type) {
+		case map[string]interface{}:
+			if err := addJSONRecords(records, key+".", value); err != nil {
+				return err
+			}
+		case []interface{}:
+			// An empty list is a key without a value, which is a boolean attribute with --syntax rfc6763
+			if len(value) == 0 {
+				*records = append(*records, key)
+			}
+			for _, item := range value {
+				scalar, err := jsonScalar(key, item)
+				if err != nil {
+					return err
+				}
+				*records = append(*records, EncodeRecord(key, scalar))
+			}
+		default:
+			scalar, err := jsonScalar(key, value)
+			if err != nil {
+				return err
+			}
+			*records = append(*records, EncodeRecord(key, scalar))
+		}
+	}
+	return nil
+}
+
+func jsonScalar(key string, value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	default:
+		return "", fmt.Errorf("unsupported value for key \"%s\" (values must be strings, numbers, booleans or arrays of them)", key)
+	}
+}
+
+// YAML files are a list of TXT strings, or a mapping of keys to values or lists of values, with nested mappings
+// flattened into dotted keys like JSON files.  Null values are keys without values, like empty lists.
+func parseYAMLRecords(data []byte) ([]string, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	records := []string{}
+	if len(document.Content) == 0 {
+		return records, nil
+	}
+	root := yamlnode.ResolveAlias(document.Content[0])
+	switch root.Kind {
+	case yaml.SequenceNode:
+		for _, item := range root.Content {
+			item = yamlnode.ResolveAlias(item)
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: item of the list isn't a string", item.Line)
+			}
+			records = append(records, item.Value)
+		}
+		return records, nil
+	case yaml.MappingNode:
+		return records, addYAMLRecords(&records, "", root)
+	default:
+		return nil, errors.New("expected a list of TXT strings, or a mapping of keys and values")
+	}
+}
+
+func addYAMLRecords(records *[]string, prefix string, node *yaml.Node) error {
+	return yamlnode.ForEachPair(node, func(name string, value *yaml.Node) error {
+		key := prefix + name
+
+		switch {
+		case value.Kind == yaml.MappingNode:
+			if err := addYAMLRecords(records, key+".", value); err != nil {
+				return err
+			}
+		case value.Kind == yaml.SequenceNode:
+			if len(value.Content) == 0 {
+				*records = append(*records, key)
+			}
+			for _, item := range value.Content {
+				item = yamlnode.ResolveAlias(item)
+				if item.Kind != yaml.ScalarNode {
+					return fmt.Errorf("line %d: unsupported value for key \"%s\" (values must be strings or lists of them)", item.Line, key)
+				}
+				*records = append(*records, EncodeRecord(key, item.Value))
+			}
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+			*records = append(*records, key)
+		default:
+			*records = append(*records, EncodeRecord(key, value.Value))
+		}
+		return nil
+	})
+}
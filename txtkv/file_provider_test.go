@@ -0,0 +1,242 @@
+package txtkv
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetTxtRecords(t *testing.T) {
+	sampleRecordsFile := strings.NewReader(`a=b
+
+"quoted=line"
+"quoted=line with \" escaped quote and \\ escaped backslash"
+"escape=sequences\x21\x21\x21"
+"dig=caf\195\169 \; \092"
+"multi=part1" "part2"
+not a record
+unquoted=\n\"record
+` + "`\"unquoted=record")
+	expected := []string{
+		"a=b",
+		"",
+		"quoted=line",
+		`quoted=line with " escaped quote and \ escaped backslash`,
+		"escape=sequences!!!",
+		`dig=café ; \`,
+		"multi=part1part2",
+		"not a record",
+		`unquoted=\n\"record`,
+		"`\"unquoted=record",
+	}
+	options := &Options{}
+	provider, err := makeFileProvider(options, "localhost", "/test/path")
+	if err != nil {
+		t.Error("Error", err.Error())
+	}
+
+	records, err := provider.getTxtRecordsFromReader(sampleRecordsFile)
+	if err != nil {
+		t.Error("Error", err.Error())
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Error("Expected\n", expected, "\nbut got\n", records)
+	}
+}
+
+const digOutput = `
+; <<>> DiG 9.18.18 <<>> foo.example.com txt
+;; global options: +cmd
+;; Got answer:
+;; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 1234
+;; flags: qr rd ra; QUERY: 1, ANSWER: 2, AUTHORITY: 0, ADDITIONAL: 1
+
+;; QUESTION SECTION:
+;foo.example.com.		IN	TXT
+
+;; ANSWER SECTION:
+foo.example.com.	300	IN	TXT	"key=" "dig"
+foo.example.com.	300	IN	TXT	"other=\"quoted\" \195\169"
+
+;; Query time: 3 msec
+;; SERVER: 127.0.0.53#53(127.0.0.53) (UDP)
+`
+
+type recordFileTestPair struct {
+	Data   string
+	Result []string
+	Err    error
+}
+
+func TestParseJSONRecords(t *testing.T) {
+	for _, testPair := range []recordFileTestPair{
+		{`["a=1", "b=two words", "not a record"]`, []string{"a=1", "b=two words", "not a record"}, nil},
+		{`[]`, []string{}, nil},
+		{`{"b": "x", "a": 1.5, "c": true}`, []string{"a=1.5", "b=x", "c=true"}, nil},
+		{`{"things": ["x", 2], "flag": []}`, []string{"flag", "things=x", "things=2"}, nil},
+		{`{"db": {"host": "h", "port": 5432}}`, []string{"db.host=h", "db.port=5432"}, nil},
+		{`{"a=b": "c"}`, []string{"a`=b=c"}, nil},
+		{`["a=1", 2]`, nil, errors.New("Non-string record")},
+		{`{"a": null}`, nil, errors.New("Null value")},
+		{`{"a": [{"b": 1}]}`, nil, errors.New("Object in array")},
+		{`"a=1"`, nil, errors.New("Not an array or object")},
+		{`["a=1"] ["b=2"]`, nil, errors.New("Two values")},
+		{`{"a": `, nil, errors.New("Invalid JSON")},
+	} {
+		result, err := parseJSONRecords([]byte(testPair.Data))
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if err == nil && !reflect.DeepEqual(result, testPair.Result) {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
+
+func TestParseYAMLRecords(t *testing.T) {
+	for _, testPair := range []recordFileTestPair{
+		{"- a=1\n- \"b=two words\"\n", []string{"a=1", "b=two words"}, nil},
+		{"b: x\na: 1\n", []string{"b=x", "a=1"}, nil},
+		{"things: [x, 2]\nflag: []\n", []string{"things=x", "things=2", "flag"}, nil},
+		{"db:\n  host: h\n  port: 5432\n", []string{"db.host=h", "db.port=5432"}, nil},
+		{"pem: |\n  -----BEGIN\n  abc\n  -----END\n", []string{"pem=-----BEGIN\nabc\n-----END\n"}, nil},
+		{"base: &base\n  a: 1\ncopy: *base\nempty:\nquoted: \"tab\\there \\u00e9\"\n", []string{"base.a=1", "copy.a=1", "empty", "quoted=tab\there é"}, nil},
+		{"", []string{}, nil},
+		{"a: 1\na: 2\n", nil, errors.New("Duplicate key")},
+		{"a: [[1]]\n", nil, errors.New("Nested list")},
+		{"a: [1\n", nil, errors.New("Invalid YAML")},
+		{"just text\n", nil, errors.New("Not a list or mapping")},
+	} {
+		result, err := parseYAMLRecords([]byte(testPair.Data))
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if err == nil && !reflect.DeepEqual(result, testPair.Result) {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
+
+type fileFormatTestPair struct {
+	Name   string
+	Query  string
+	Data   string
+	Result string
+}
+
+// The format comes from the format parameter, the file extension, or the contents
+func TestFileFormats(t *testing.T) {
+	dir := t.TempDir()
+	client := NewClient(Options{})
+	for _, testPair := range []fileFormatTestPair{
+		{"records", "", "\"key=lines\"\n", "lines"},
+		{"records.json", "", `{"key": "json"}`, "json"},
+		{"records.JSON", "", `["key=json"]`, "json"},
+		{"records.yaml", "", "key: yaml\n", "yaml"},
+		{"records.yml", "", "key: yaml\n", "yaml"},
+		{"records.txt", "?format=json", `{"key": "json"}`, "json"},
+		{"records.json", "?format=lines", "key=lines\n", "lines"},
+		{"records", "", digOutput, "dig"},
+		{"records", "", "server = db1\nkey=lines\n", "lines"},
+		{"records", "", "; comment\nkey=lines\n", "lines"},
+		{"records", "?format=dig", "foo.example.com. 300 IN TXT \"key=dig\"\n", "dig"},
+		{"records", "?format=host", "foo.example.com descriptive text \"key=host\"\n", "host"},
+		{"records", "?format=dig", "server 10.0.0.1\nupdate add foo.example.com 300 TXT \"key=nsupdate\"\nsend\n", "nsupdate"},
+		{"records", "?format=dig&owner=bar.example.com", "foo.example.com. IN TXT \"key=foo\"\nbar.example.com. IN TXT \"key=bar\"\n", "bar"},
+		{"records", "?format=dig", "foo.example.com. IN TXT \"key=\" \"dig\"\n", "dig"},
+	} {
+		path := filepath.Join(dir, testPair.Name)
+		if err := os.WriteFile(path, []byte(testPair.Data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := client.Lookup(context.Background(), "file:"+path+testPair.Query, "key")
+		if err != nil || result != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", result, err, "for", testPair)
+		}
+	}
+}
+
+type detectFileFormatTestPair struct {
+	Data   string
+	Result string
+}
+
+func TestDetectFileFormat(t *testing.T) {
+	for _, testPair := range []detectFileFormatTestPair{
+		{"", fileFormatLines},
+		{"a=b\n", fileFormatLines},
+		{"\"a=b\"\n", fileFormatLines},
+		{"not a record\n", fileFormatLines},
+		{"zone=example.com\n", fileFormatLines},
+		{digOutput, fileFormatDig},
+		{"server = db1\n", fileFormatLines},
+		{"; just a comment\na=b\n", fileFormatLines},
+		{"foo.example.com.\t300\tIN\tTXT\t\"a=b\"\n", fileFormatLines},
+		{"foo.example.com descriptive text \"a=b\"\n", fileFormatLines},
+		{"update add foo.example.com 300 TXT \"a=b\"\n", fileFormatLines},
+	} {
+		if result := detectFileFormat([]byte(testPair.Data)); result != testPair.Result {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
+
+type zoneRecordsTestPair struct {
+	Zone   string
+	Owner  string
+	Syntax string
+	Result []string
+	Err    error
+}
+
+func TestParseZoneRecords(t *testing.T) {
+	twoNames := "foo.example.com. IN TXT \"a=1\"\nbar.example.com. IN TXT \"a=2\" \"3\"\nfoo.example.com. IN A 192.0.2.1\n"
+	for _, testPair := range []zoneRecordsTestPair{
+		{digOutput, "", "", []string{"key=dig", `other="quoted" é`}, nil},
+		{digOutput, "FOO.example.com", "", []string{"key=dig", `other="quoted" é`}, nil},
+		{digOutput, "", SyntaxRFC6763, []string{"key=", "dig", `other="quoted" é`}, nil},
+		{twoNames, "foo.example.com", "", []string{"a=1"}, nil},
+		{twoNames, "bar.example.com.", "", []string{"a=23"}, nil},
+		{twoNames, "", "", nil, errors.New("More than one name")},
+		{twoNames, "baz.example.com", "", nil, ErrNoRecords},
+		{"; nothing\n", "", "", []string{}, nil},
+		{"foo.example.com. IN TXT \"unterminated\n", "", "", nil, errors.New("Invalid record")},
+		{nsupdateToZone([]byte("zone example.com\nupdate delete foo.example.com. TXT\nupdate add foo.example.com. 60 TXT \"a=1\"\nsend\n")), "", "", []string{"a=1"}, nil},
+		{hostToZone([]byte("foo.example.com descriptive text \"a=1\" \"2\"\nHost bar.example.com not found: 3(NXDOMAIN)\n")), "", "", []string{"a=12"}, nil},
+	} {
+		result, err := parseZoneRecords(testPair.Zone, testPair.Owner, testPair.Syntax)
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if testPair.Err == ErrNoRecords && !errors.Is(err, ErrNoRecords) {
+			t.Error("Expected ErrNoRecords but got", err, "for", testPair)
+		}
+
+		if err == nil && !reflect.DeepEqual(result, testPair.Result) {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}
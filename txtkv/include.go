@@ -0,0 +1,89 @@
+package txtkv
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// IncludeKey is the reserved key for include directives.  Its values are sources (URIs or domain names).
+const IncludeKey = "sdget-include"
+
+const defaultMaxIncludeDepth = 8
+
+func (c *Client) expandIncludes(ctx context.Context, table *Table, chain []string, visited map[string]bool) (*Table, error) {
+	// TXT records come in no particular order, so neither can includes
+	includes := append([]string(nil), table.LookupList(IncludeKey)...)
+	if len(includes) == 0 {
+		return table, nil
+	}
+	sort.Strings(includes)
+
+	maxDepth := c.options.MaxIncludeDepth
+	if maxDepth == 0 {
+		maxDepth = defaultMaxIncludeDepth
+	}
+	if len(chain) > maxDepth {
+		return nil, fmt.Errorf("%w (%d): %s", ErrIncludeDepth, maxDepth, strings.Join(chain, " -> "))
+	}
+
+	// All the includes at this level are fetched at once.  Some might turn out to be included by an earlier sibling, and
+	// not needed here after all, but that's rare, and fetching them is harmless.
+	// Network sources can't include local ones
+	from := chain[len(chain)-1]
+	var fetches []string
+	for _, include := range includes {
+		if !visited[canonicalSource(strings.TrimSpace(include))] && checkReference(from, strings.TrimSpace(include)) == nil {
+			fetches = append(fetches, strings.TrimSpace(include))
+		}
+	}
+	fetched := make(map[string]fetchResult)
+	for i, result := range fetchAll(ctx, fetches, c.fetchTable) {
+		fetched[fetches[i]] = result
+	}
+
+	var includedTables []*Table
+	for _, include := range includes {
+		include = strings.TrimSpace(include)
+		if err := checkReference(from, include); err != nil {
+			return nil, fmt.Errorf("error including %s from %s: %w", include, from, err)
+		}
+		includeChain := append(append([]string(nil), chain...), include)
+		for _, ancestor := range chain {
+			if canonicalSource(ancestor) == canonicalSource(include) {
+				return nil, fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(includeChain, " -> "))
+			}
+		}
+		// The same source included twice from different places only counts once
+		if visited[canonicalSource(include)] {
+			continue
+		}
+		visited[canonicalSource(include)] = true
+
+		includedTable, err := fetched[include].table, fetched[include].err
+		if err != nil {
+			return nil, fmt.Errorf("error including %s from %s: %w", include, from, err)
+		}
+		includedTable, err = c.expandIncludes(ctx, includedTable, includeChain, visited)
+		if err != nil {
+			return nil, err
+		}
+		includedTables = append(includedTables, includedTable)
+	}
+
+	included, err := MergeTables(LayerMerge, includedTables...)
+	if err != nil {
+		return nil, err
+	}
+	return MergeTables(LayerFirstWins, table, included)
+}
+
+// Different spellings of the same DNS source are treated as the same source
+func canonicalSource(source string) string {
+	source = strings.ToLower(strings.TrimSpace(source))
+	if !strings.ContainsRune(source, ':') {
+		source = "dns:" + source
+	}
+	return strings.TrimSuffix(source, ".")
+}
@@ -0,0 +1,122 @@
+package txtkv
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type includeTestPair struct {
+	Key     string
+	Values  []string
+	Sources []string
+}
+
+func TestIncludes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"service": "sdget-include=file:" + dir + "/shared\nsdget-include=file:" + dir + "/region\nport=8080\nname=service",
+		"shared":  "sdget-include=file:" + dir + "/global\nport=80\nlog-level=info",
+		"region":  "sdget-include=file:" + dir + "/global\nregion=ap-southeast-2\nlog-level=debug",
+		"global":  "owner=platform\nport=1",
+		"cycle-a": "sdget-include=file:" + dir + "/cycle-b\na=1",
+		"cycle-b": "sdget-include=file:" + dir + "/cycle-a\nb=1",
+		"deep-1":  "sdget-include=file:" + dir + "/deep-2",
+		"deep-2":  "sdget-include=file:" + dir + "/deep-3",
+		"deep-3":  "deep=1",
+	}
+	for name, records := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(records), 0644); err != nil {
+			t.Fatal("Error", err.Error())
+		}
+	}
+	ctx := context.Background()
+	source := "file:" + dir + "/service"
+
+	client := NewClient(Options{Includes: true})
+	table, err := client.Table(ctx, source)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	for _, testPair := range []includeTestPair{
+		{"name", []string{"service"}, []string{source}},
+		{"port", []string{"8080"}, []string{source}},
+		{"region", []string{"ap-southeast-2"}, []string{"file:" + dir + "/region"}},
+		// global is included twice, but only counted once
+		{"owner", []string{"platform"}, []string{"file:" + dir + "/global"}},
+		// Conflicting values from sibling includes are all kept
+		{"log-level", []string{"debug", "info"}, []string{"file:" + dir + "/region", "file:" + dir + "/shared"}},
+	} {
+		if values := table.LookupList(testPair.Key); !reflect.DeepEqual(values, testPair.Values) {
+			t.Error("Expected", testPair.Values, "but got", values, "for", testPair)
+		}
+		if sources := table.LookupSources(testPair.Key); !reflect.DeepEqual(sources, testPair.Sources) {
+			t.Error("Expected", testPair.Sources, "but got", sources, "for", testPair)
+		}
+	}
+
+	if _, err := client.Table(ctx, "file:"+dir+"/cycle-a"); !errors.Is(err, ErrIncludeCycle) {
+		t.Error("Expected ErrIncludeCycle but got", err)
+	}
+
+	if _, err := client.Table(ctx, "file:"+dir+"/deep-1"); err != nil {
+		t.Error("Error", err.Error())
+	}
+	shallowClient := NewClient(Options{Includes: true, MaxIncludeDepth: 1})
+	if _, err := shallowClient.Table(ctx, "file:"+dir+"/deep-1"); !errors.Is(err, ErrIncludeDepth) {
+		t.Error("Expected ErrIncludeDepth but got", err)
+	}
+
+	// Includes are opt-in
+	table, err = NewClient(Options{}).Table(ctx, source)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if values := table.LookupList("region"); len(values) != 0 {
+		t.Error("Expected no values but got", values)
+	}
+}
+
+type networkIncludeTestPair struct {
+	Source string
+	Err    error
+}
+
+func TestNetworkIncludes(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "local"), []byte("sdget-include=shared.example.com\nsecret=hunter2"), 0644); err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	nameserver := startTestServer(t, "127.0.0.1:0", []string{
+		`shared.example.com. 300 IN TXT "region=ap-southeast-2"`,
+		`dns.example.com. 300 IN TXT "sdget-include=shared.example.com"`,
+		`file.example.com. 300 IN TXT "sdget-include=file:` + dir + `/local"`,
+		`snapshot.example.com. 300 IN TXT "sdget-include=snapshot:` + dir + `/local"`,
+		`indirect.example.com. 300 IN TXT "sdget-include=dns:dns.example.com"`,
+		`indirect.example.com. 300 IN TXT "sdget-include=FILE:` + dir + `/local"`,
+	})
+	client := NewClient(Options{Nameserver: nameserver, Includes: true})
+
+	for _, testPair := range []networkIncludeTestPair{
+		{"dns.example.com", nil},
+		{"file:" + dir + "/local", nil},
+		{"file.example.com", ErrLocalReference},
+		{"snapshot.example.com", ErrLocalReference},
+		{"indirect.example.com", ErrLocalReference},
+	} {
+		table, err := client.Table(context.Background(), testPair.Source)
+		if !errors.Is(err, testPair.Err) {
+			t.Error("Expected", testPair.Err, "but got", err, "for", testPair.Source)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(table.LookupList("region"), []string{"ap-southeast-2"}) {
+			t.Error("Expected region from the included DNS source but got", table.LookupList("region"), "for", testPair.Source)
+		}
+		if err != nil && table != nil {
+			t.Error("Expected no table for", testPair.Source, "but got", table)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package txtkv
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type interpolation struct {
+	ctx    context.Context
+	client *Client
+	tables map[string]*Table
+	// References being expanded, for cycle detection
+	chain []string
+}
+
+// Interpolate expands references in the value of a key from a table.
+//
+// "${name}" is replaced by the value of another key in the same table, and "${uri/name}" (like
+// "${dns:shared.example.com/region}") by the value of a key in another source.  (References to other sources need a
+// URI, so that they can't be confused with keys.)  Referenced keys must have exactly one value, which is expanded in
+// turn, relative to its own source.  Chunked values are reassembled, and encrypted values are decrypted with
+// Options.DecryptKeys, so that ciphertext never ends up in an expanded value.  "$$" is a literal "$".
+//
+// Values from network sources (like DNS) can only reference other network sources, and references from them to local
+// sources (like files) are errors matching ErrLocalReference.  Values with no source (like defaults) and values from
+// local sources can reference anything.
+//
+// Cycles are errors matching ErrInterpolationCycle, and encrypted values that can't be decrypted are errors matching
+// ErrDecryption.
+func (c *Client) Interpolate(ctx context.Context, table *Table, key string, value string) (string, error) {
+	in := &interpolation{
+		ctx:    ctx,
+		client: c,
+		tables: make(map[string]*Table),
+	}
+	return in.expand(table, "", strings.ToLower(key), value)
+}
+
+func (in *interpolation) expand(table *Table, source string, key string, value string) (string, error) {
+	reference := key
+	if source != "" {
+		reference = source + "/" + key
+	}
+	for _, ancestor := range in.chain {
+		if ancestor == reference {
+			return "", fmt.Errorf("%w: %s", ErrInterpolationCycle, strings.Join(append(in.chain, reference), " -> "))
+		}
+	}
+	in.chain = append(in.chain, reference)
+	defer func() { in.chain = in.chain[:len(in.chain)-1] }()
+
+	var builder strings.Builder
+	for i := 0; i < len(value); {
+		if value[i] != '$' || i+1 == len(value) {
+			builder.WriteByte(value[i])
+			i++
+			continue
+		}
+		switch value[i+1] {
+		case '$':
+			builder.WriteByte('$')
+			i += 2
+
+		case '{':
+			end := strings.IndexByte(value[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated reference in value of key %s: %s", reference, value[i:])
+			}
+			expanded, err := in.resolve(table, source, valueSource(table, source, key), value[i+2:i+2+end])
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(expanded)
+			i += end + 3
+
+		default:
+			builder.WriteByte('$')
+			i++
+		}
+	}
+	return builder.String(), nil
+}
+
+// Where a value came from: values of a referenced source's table came from that source, and others from whichever
+// source has the key (or the caller, for default values).  Values of a key in several sources count as coming from the
+// network if any of them does.
+func valueSource(table *Table, source string, key string) string {
+	if source != "" {
+		return source
+	}
+	sources := table.sources[key]
+	if len(sources) == 0 {
+		sources = table.sources[ChunkKey(key, 0)]
+	}
+	for _, source := range sources {
+		if isNetworkSource(source) {
+			return source
+		}
+	}
+	if len(sources) > 0 {
+		return sources[0]
+	}
+	return ""
+}
+
+func (in *interpolation) resolve(table *Table, source string, from string, reference string) (string, error) {
+	key := reference
+	if i := strings.LastIndex(reference, "/"); i >= 0 && strings.Contains(reference[:i], ":") {
+		source, key = reference[:i], reference[i+1:]
+		if err := checkReference(from, source); err != nil {
+			return "", fmt.Errorf("error interpolating ${%s}: %w", reference, err)
+		}
+		table = in.tables[source]
+		if table == nil {
+			var err error
+			table, err = in.client.Table(in.ctx, source)
+			if err != nil {
+				return "", fmt.Errorf("error interpolating ${%s}: %w", reference, err)
+			}
+			in.tables[source] = table
+		}
+	}
+	key = strings.ToLower(key)
+
+	value, isChunked, err := table.ReassembleChunks(key)
+	if err == nil && !isChunked {
+		value, err = table.Lookup(key)
+	}
+	if err == nil {
+		value, err = DecryptValue(in.client.options.DecryptKeys, key, value)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error interpolating ${%s}: %w", reference, err)
+	}
+	return in.expand(table, source, key, value)
+}
@@ -0,0 +1,121 @@
+package txtkv
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+type interpolateTestPair struct {
+	Value    string
+	Expected string
+	Err      error
+}
+
+func TestInterpolate(t *testing.T) {
+	shared := writeTestRecords(t, `"region=ap-southeast-2"
+"zone=${region}a"
+`)
+	table := ParseRecords([]string{
+		"host=example.com",
+		"port=8443",
+		"url=https://${host}:${port}/",
+		"self=${self}",
+		"ping=${pong}",
+		"pong=${ping}",
+		"list=a",
+		"list=b",
+		"cert#0=ab",
+		"cert#1=cd",
+		"cert#n=2",
+	})
+	client := NewClient(Options{})
+
+	for _, testPair := range []interpolateTestPair{
+		{"plain", "plain", nil},
+		{"https://${host}:${port}/", "https://example.com:8443/", nil},
+		{"${url}api", "https://example.com:8443/api", nil},
+		{"${HOST}", "example.com", nil},
+		{"$$HOME costs $$5", "$HOME costs $5", nil},
+		{"$5 and $", "$5 and $", nil},
+		{"${cert}", "abcd", nil},
+		{"${" + shared + "/region}", "ap-southeast-2", nil},
+		{"${" + shared + "/zone}", "ap-southeast-2a", nil},
+		{"${missing}", "", ErrKeyNotFound},
+		{"${list}", "", ErrTooManyValues},
+		{"${self}", "", ErrInterpolationCycle},
+		{"${ping}", "", ErrInterpolationCycle},
+		{"${host", "", errors.New("Unterminated")},
+		{"${file:///nonexistent/records/region}", "", errors.New("Missing source")},
+	} {
+		value, err := client.Interpolate(context.Background(), table, "value", testPair.Value)
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if err != nil && (testPair.Err == ErrKeyNotFound || testPair.Err == ErrTooManyValues || testPair.Err == ErrInterpolationCycle) && !errors.Is(err, testPair.Err) {
+			t.Error("Expected", testPair.Err, "but got", err, "for", testPair)
+		}
+
+		if value != testPair.Expected {
+			t.Error("Expected", testPair.Expected, "but got", value, "for", testPair)
+		}
+	}
+}
+
+func TestInterpolateEncrypted(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	encrypted, err := EncryptValue(key.PublicKey(), "password", "s3cret")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	table := ParseRecords([]string{"password=" + encrypted, "moved=" + encrypted})
+
+	client := NewClient(Options{DecryptKeys: []*ecdh.PrivateKey{key}})
+	value, err := client.Interpolate(context.Background(), table, "dsn", "postgres://app:${password}@db/")
+	if err != nil || value != "postgres://app:s3cret@db/" {
+		t.Error("Expected postgres://app:s3cret@db/ but got", value, err)
+	}
+	if _, err := client.Interpolate(context.Background(), table, "dsn", "${moved}"); !errors.Is(err, ErrDecryption) {
+		t.Error("Expected ErrDecryption for a value moved to another key but got", err)
+	}
+
+	client = NewClient(Options{})
+	if value, err := client.Interpolate(context.Background(), table, "dsn", "${password}"); !errors.Is(err, ErrNoDecryptionKey) {
+		t.Error("Expected ErrNoDecryptionKey without decryption keys but got", value, err)
+	}
+}
+
+func TestInterpolateLocalReferences(t *testing.T) {
+	local := writeTestRecords(t, `"secret=hunter2"
+"copy=${secret}"
+`)
+	client := NewClient(Options{})
+	for _, source := range []string{"foo.example.com", "dns:foo.example.com", "dns://10.0.0.2/foo.example.com", "mdns:printer.local"} {
+		table := parseRecordsFrom([]string{"leak=${" + local + "/secret}", "chunk#0=${" + local + "/secret}", "chunk#n=1"}, source, SyntaxRFC1464)
+		for _, key := range []string{"leak", "chunk"} {
+			value, err := client.Interpolate(context.Background(), table, key, "${"+local+"/secret}")
+			if !errors.Is(err, ErrLocalReference) {
+				t.Error("Expected ErrLocalReference for", key, "from", source, "but got", value, err)
+			}
+		}
+	}
+
+	// Local values and defaults can reference local sources
+	table := parseRecordsFrom([]string{"copy=${" + local + "/copy}"}, local, SyntaxRFC1464)
+	for _, key := range []string{"copy", "missing"} {
+		if value, err := client.Interpolate(context.Background(), table, key, "${"+local+"/copy}"); err != nil || value != "hunter2" {
+			t.Error("Expected hunter2 for", key, "but got", value, err)
+		}
+	}
+}
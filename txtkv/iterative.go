@@ -0,0 +1,273 @@
+package txtkv
+
+// Iterative resolution, starting from the root servers and following referrals
+// This is for hosts that can reach authoritative nameservers but have no usable recursive resolver.
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// https://www.internic.net/domain/named.root
+var defaultRootServers = []string{
+	"198.41.0.4",
+	"199.9.14.201",
+	"192.33.4.12",
+	"199.7.91.13",
+	"192.203.230.10",
+	"192.5.5.241",
+	"192.112.36.4",
+	"198.97.190.53",
+	"192.36.148.17",
+	"192.58.128.30",
+	"193.0.14.129",
+	"199.7.83.42",
+	"202.12.27.33",
+	"2001:503:ba3e::2:30",
+	"2001:500:200::b",
+	"2001:500:2::c",
+	"2001:500:2d::d",
+	"2001:500:a8::e",
+	"2001:500:2f::f",
+	"2001:500:12::d0d",
+	"2001:500:1::53",
+	"2001:7fe::53",
+	"2001:503:c27::2:30",
+	"2001:7fd::1",
+	"2001:500:9f::42",
+	"2001:dc3::35",
+}
+
+// Limits to stop misconfigured (or malicious) delegations from sending us around in circles
+const maxReferrals = 16
+const maxResolutionDepth = 8
+
+type iterativeResolver struct {
+	rootServers []string
+	// Port used for all nameservers found through delegations
+	port string
+}
+
+func makeIterativeResolver(options *Options) (*iterativeResolver, error) {
+	rootServers := defaultRootServers
+	if options.RootHints != "" {
+		hints, err := os.Open(options.RootHints)
+		if err != nil {
+			return nil, fmt.Errorf("error opening root hints file: %w", err)
+		}
+		defer hints.Close()
+		rootServers, err = readRootHints(hints, options.RootHints)
+		if err != nil {
+			return nil, err
+		}
+	}
+	resolver := &iterativeResolver{port: "53"}
+	for _, server := range rootServers {
+		address, err := addNameserverPort(options, server)
+		if err != nil {
+			return nil, err
+		}
+		resolver.rootServers = append(resolver.rootServers, address)
+	}
+	return resolver, nil
+}
+
+// Root hints use the zone file format of named.root, but only the addresses matter to us
+func readRootHints(hints io.Reader, filename string) ([]string, error) {
+	var addresses []string
+	parser := dns.NewZoneParser(hints, ".", filename)
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		switch rr := rr.(type) {
+		case *dns.A:
+			addresses = append(addresses, rr.A.String())
+		case *dns.AAAA:
+			addresses = append(addresses, rr.AAAA.String())
+		}
+	}
+	if err := parser.Err(); err != nil {
+		return nil, fmt.Errorf("error reading root hints: %w", err)
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no root server addresses found in root hints file %s", filename)
+	}
+	return addresses, nil
+}
+
+func (r *iterativeResolver) resolve(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	return r.resolveFrom(ctx, name, qtype, 0)
+}
+
+func (r *iterativeResolver) resolveFrom(ctx context.Context, name string, qtype uint16, depth int) (*dns.Msg, error) {
+	if depth > maxResolutionDepth {
+		return nil, fmt.Errorf("too many levels of indirection resolving %s", name)
+	}
+
+	zone := "."
+	servers := r.rootServers
+	for referrals := 0; referrals <= maxReferrals; referrals++ {
+		response, err := r.queryServers(ctx, servers, name, qtype)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.Rcode == dns.RcodeNameError {
+			return response, nil
+		}
+
+		if len(response.Answer) > 0 {
+			return r.followCNAME(ctx, response, name, qtype, depth)
+		}
+
+		if response.Authoritative {
+			// No data for this name and type
+			return response, nil
+		}
+
+		nextZone, nameservers := referral(response, zone, name)
+		if nameservers == nil {
+			return nil, fmt.Errorf("lame delegation for %s: no answer or referral from nameservers for %s", name, zone)
+		}
+		servers, err = r.delegatedServers(ctx, response, nameservers, depth)
+		if err != nil {
+			return nil, err
+		}
+		zone = nextZone
+	}
+	return nil, fmt.Errorf("too many referrals resolving %s", name)
+}
+
+func (r *iterativeResolver) queryServers(ctx context.Context, servers []string, name string, qtype uint16) (*dns.Msg, error) {
+	query := new(dns.Msg)
+	query.SetQuestion(name, qtype)
+	query.RecursionDesired = false
+
+	var lastErr error
+	for _, server := range servers {
+		response, err := exchange(ctx, query, server)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+			lastErr = fmt.Errorf("%w %s: %s", ErrServerFailure, server, dns.RcodeToString[response.Rcode])
+			continue
+		}
+		return response, nil
+	}
+	if lastErr == nil {
+		return nil, fmt.Errorf("no nameservers to query for %s", name)
+	}
+	return nil, fmt.Errorf("no usable nameserver for %s: %w", name, lastErr)
+}
+
+// Answers can be aliases that need resolving again from the top
+func (r *iterativeResolver) followCNAME(ctx context.Context, response *dns.Msg, name string, qtype uint16, depth int) (*dns.Msg, error) {
+	var target string
+	for _, answer := range response.Answer {
+		if answer.Header().Rrtype == qtype {
+			return response, nil
+		}
+		if cname, ok := answer.(*dns.CNAME); ok && strings.EqualFold(cname.Hdr.Name, name) {
+			target = cname.Target
+		}
+	}
+	if target == "" || qtype == dns.TypeCNAME {
+		return response, nil
+	}
+	targetResponse, err := r.resolveFrom(ctx, target, qtype, depth+1)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving CNAME target %s: %w", target, err)
+	}
+	targetResponse.Answer = append(response.Answer, targetResponse.Answer...)
+	return targetResponse, nil
+}
+
+// Referrals are only followed if they get closer to the name being resolved
+func referral(response *dns.Msg, zone string, name string) (string, []string) {
+	var nextZone string
+	var nameservers []string
+	for _, rr := range response.Ns {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		owner := ns.Hdr.Name
+		if !dns.IsSubDomain(owner, name) || !dns.IsSubDomain(zone, owner) || dns.CountLabel(owner) <= dns.CountLabel(zone) {
+			continue
+		}
+		if nextZone != "" && !strings.EqualFold(owner, nextZone) {
+			continue
+		}
+		nextZone = owner
+		nameservers = append(nameservers, ns.Ns)
+	}
+	return nextZone, nameservers
+}
+
+func (r *iterativeResolver) delegatedServers(ctx context.Context, response *dns.Msg, nameservers []string, depth int) ([]string, error) {
+	glue := make(map[string][]string)
+	for _, rr := range response.Extra {
+		owner := strings.ToLower(rr.Header().Name)
+		switch rr := rr.(type) {
+		case *dns.A:
+			glue[owner] = append(glue[owner], rr.A.String())
+		case *dns.AAAA:
+			glue[owner] = append(glue[owner], rr.AAAA.String())
+		}
+	}
+
+	var servers []string
+	for _, nameserver := range nameservers {
+		for _, address := range glue[strings.ToLower(nameserver)] {
+			servers = append(servers, net.JoinHostPort(address, r.port))
+		}
+	}
+	if len(servers) > 0 {
+		return servers, nil
+	}
+
+	// No glue, so the nameservers' addresses need to be looked up separately
+	var lastErr error
+	for _, nameserver := range nameservers {
+		addresses, err := r.resolveAddresses(ctx, nameserver, depth+1)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, address := range addresses {
+			servers = append(servers, net.JoinHostPort(address, r.port))
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no addresses found for nameservers %s: %w", strings.Join(nameservers, ", "), lastErr)
+	}
+	return servers, nil
+}
+
+func (r *iterativeResolver) resolveAddresses(ctx context.Context, name string, depth int) ([]string, error) {
+	var addresses []string
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		response, err := r.resolveFrom(ctx, name, qtype, depth)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving address of nameserver %s: %w", name, err)
+		}
+		for _, answer := range response.Answer {
+			switch rr := answer.(type) {
+			case *dns.A:
+				addresses = append(addresses, rr.A.String())
+			case *dns.AAAA:
+				addresses = append(addresses, rr.AAAA.String())
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no addresses found for nameserver %s", name)
+	}
+	return addresses, nil
+}
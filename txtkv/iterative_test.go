@@ -0,0 +1,89 @@
+package txtkv
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func startTestHierarchy(t *testing.T) *iterativeResolver {
+	root := startTestServer(t, "127.0.0.1:0", []string{
+		"com. 300 IN NS a.gtld.com.",
+		"net. 300 IN NS a.gtld.com.",
+		"a.gtld.com. 300 IN A 127.0.0.2",
+	})
+	_, port, _ := net.SplitHostPort(root)
+	startTestServer(t, "127.0.0.2:"+port, []string{
+		"a.gtld.com. 300 IN A 127.0.0.2",
+		// No glue for an out-of-bailiwick nameserver
+		"example.com. 300 IN NS ns.example.net.",
+		"example.net. 300 IN NS ns.example.net.",
+		"ns.example.net. 300 IN A 127.0.0.3",
+	})
+	startTestServer(t, "127.0.0.3:"+port, []string{
+		"ns.example.net. 300 IN A 127.0.0.3",
+		`foo.example.com. 300 IN TXT "key=value"`,
+		`foo.example.com. 300 IN TXT "things=item1"`,
+		"alias.example.com. 300 IN CNAME foo.example.com.",
+	})
+	return &iterativeResolver{
+		rootServers: []string{root},
+		port:        port,
+	}
+}
+
+type iterativeResolveTestPair struct {
+	Name   string
+	Rcode  int
+	Result []string
+}
+
+func TestIterativeResolve(t *testing.T) {
+	resolver := startTestHierarchy(t)
+	for _, testPair := range []iterativeResolveTestPair{
+		{"foo.example.com.", dns.RcodeSuccess, []string{"key=value", "things=item1"}},
+		{"alias.example.com.", dns.RcodeSuccess, []string{"key=value", "things=item1"}},
+		{"ns.example.net.", dns.RcodeSuccess, nil},
+		{"nosuchname.example.com.", dns.RcodeNameError, nil},
+	} {
+		response, err := resolver.resolve(context.Background(), testPair.Name, dns.TypeTXT)
+		if err != nil {
+			t.Error("Error", err.Error(), "for", testPair)
+			continue
+		}
+		if response.Rcode != testPair.Rcode {
+			t.Error("Expected", dns.RcodeToString[testPair.Rcode], "but got", dns.RcodeToString[response.Rcode], "for", testPair)
+		}
+		records, err := txtAnswers(response)
+		if err != nil {
+			t.Error("Error", err.Error(), "for", testPair)
+		}
+		if !reflect.DeepEqual(records, testPair.Result) {
+			t.Error("Expected", testPair.Result, "but got", records, "for", testPair)
+		}
+	}
+}
+
+func TestReadRootHints(t *testing.T) {
+	hints := strings.NewReader(`; root hints
+.                        3600000      NS    A.ROOT-SERVERS.NET.
+A.ROOT-SERVERS.NET.      3600000      A     198.41.0.4
+A.ROOT-SERVERS.NET.      3600000      AAAA  2001:503:ba3e::2:30
+`)
+	expected := []string{"198.41.0.4", "2001:503:ba3e::2:30"}
+	addresses, err := readRootHints(hints, "named.root")
+	if err != nil {
+		t.Error("Error", err.Error())
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Error("Expected", expected, "but got", addresses)
+	}
+
+	if _, err := readRootHints(strings.NewReader(""), "empty"); err == nil {
+		t.Error("Expected error for empty root hints")
+	}
+}
@@ -0,0 +1,179 @@
+package txtkv
+
+// Multicast DNS one-shot queries (https://tools.ietf.org/html/rfc6762#section-5.1)
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const defaultMulticastWindow = time.Second
+
+// Overridden in tests
+var mdnsGroup net.Addr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+type mdnsResolver struct {
+	group  net.Addr
+	window time.Duration
+}
+
+type mdnsProvider struct {
+	options  *Options
+	resolver *mdnsResolver
+	name     string
+}
+
+func init() {
+	RegisterScheme("mdns", Scheme{
+		Components: ComponentQuery,
+		Params:     []string{"window"},
+		New:        newMulticastProvider,
+		Network:    true,
+	})
+}
+
+func newMulticastProvider(options *Options, uri *URI) (Provider, error) {
+	if uri.Path == "" {
+		return nil, errors.New("non-empty domain name required")
+	}
+	provider := &mdnsProvider{
+		options:  options,
+		resolver: makeMulticastResolver(options),
+		name:     dns.Fqdn(uri.Path),
+	}
+	if window, ok := uri.param("window"); ok {
+		var err error
+		provider.resolver.window, err = time.ParseDuration(window)
+		if err != nil || provider.resolver.window <= 0 {
+			return nil, fmt.Errorf("invalid window \"%s\": must be a positive duration like 2s", window)
+		}
+	}
+	return provider, nil
+}
+
+func (m *mdnsProvider) TxtRecords(ctx context.Context) ([]string, error) {
+	response, err := m.resolver.resolve(ctx, m.name, dns.TypeTXT)
+	if err != nil {
+		return nil, err
+	}
+	if response.Rcode == dns.RcodeNameError {
+		return nil, fmt.Errorf("%w: no mDNS responses for %s", ErrNoRecords, m.name)
+	}
+	if m.options.Syntax == SyntaxRFC6763 {
+		return txtStrings(response)
+	}
+	return txtAnswers(response)
+}
+
+func makeMulticastResolver(options *Options) *mdnsResolver {
+	window := options.MulticastWindow
+	if window <= 0 {
+		window = defaultMulticastWindow
+	}
+	return &mdnsResolver{
+		group:  mdnsGroup,
+		window: window,
+	}
+}
+
+func (m *mdnsProvider) recordName() string {
+	return m.name
+}
+
+// isMulticastName is true for names that RFC 6762 says should be resolved with mDNS
+func isMulticastName(name string) bool {
+	return dns.IsSubDomain("local.", dns.Fqdn(name))
+}
+
+// resolve sends a one-shot query, and collects the answers from every responder until the window closes.  There's no
+// way to know when all responders have answered, so this always takes the whole window.
+//
+// Queries are sent from an ephemeral port, so responders reply with unicast.  mDNS has no negative answers, so the
+// result has an NXDOMAIN response code if nothing answered.
+func (m *mdnsResolver) resolve(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, fmt.Errorf("%w opening mDNS socket: %w", ErrNetwork, err)
+	}
+	defer conn.Close()
+
+	query := new(dns.Msg)
+	query.SetQuestion(dns.Fqdn(name), qtype)
+	query.RecursionDesired = false
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("error packing mDNS query: %w", err)
+	}
+	traceQuery(ctx, query, m.group.String(), "multicast UDP")
+	if _, err := conn.WriteTo(packed, m.group); err != nil {
+		return nil, fmt.Errorf("%w sending mDNS query: %w", ErrNetwork, err)
+	}
+
+	deadline := time.Now().Add(m.window)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("error setting mDNS socket deadline: %w", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	result := new(dns.Msg)
+	result.SetReply(query)
+	seen := make(map[string]bool)
+	buffer := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				break
+			}
+			return nil, fmt.Errorf("%w reading mDNS responses: %w", ErrNetwork, err)
+		}
+		response := new(dns.Msg)
+		if err := response.Unpack(buffer[:n]); err != nil || !response.Response {
+			// Other traffic on the link isn't our problem
+			continue
+		}
+		traceResponse(ctx, response, from.String())
+		for _, rr := range append(response.Answer, response.Extra...) {
+			header := rr.Header()
+			if header.Rrtype != qtype || !strings.EqualFold(header.Name, query.Question[0].Name) {
+				continue
+			}
+			// The top bit of the class is the cache-flush bit
+			header.Class &^= 1 << 15
+			// Responders can have different TTLs for the same record
+			ttl := header.Ttl
+			header.Ttl = 0
+			key := rr.String()
+			header.Ttl = ttl
+			if !seen[key] {
+				seen[key] = true
+				result.Answer = append(result.Answer, rr)
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("error collecting mDNS responses: %w", err)
+	}
+
+	if len(result.Answer) == 0 {
+		result.Rcode = dns.RcodeNameError
+	}
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package txtkv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Starts an in-process mDNS responder on a loopback UDP socket, which stands in for the multicast group.  Each answer
+// is sent in a separate packet, and twice, like from several responders.  Like real responders, it stays silent if it
+// has no answers.
+func startTestResponder(t *testing.T, records []string) net.Addr {
+	var rrs []dns.RR
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatal("Error", err.Error(), "parsing test record", record)
+		}
+		rrs = append(rrs, rr)
+	}
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buffer := make([]byte, 9000)
+		for {
+			n, from, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			query := new(dns.Msg)
+			if err := query.Unpack(buffer[:n]); err != nil {
+				continue
+			}
+			for _, answer := range testServerResponse(rrs, query).Answer {
+				response := new(dns.Msg)
+				response.SetReply(query)
+				response.Authoritative = true
+				response.Answer = []dns.RR{answer}
+				packed, _ := response.Pack()
+				conn.WriteTo(packed, from)
+				conn.WriteTo(packed, from)
+			}
+		}
+	}()
+	return conn.LocalAddr()
+}
+
+func useTestResponder(t *testing.T, records []string) {
+	group := mdnsGroup
+	mdnsGroup = startTestResponder(t, records)
+	t.Cleanup(func() { mdnsGroup = group })
+}
+
+func TestMulticastProvider(t *testing.T) {
+	useTestResponder(t, []string{
+		`printer.local. 120 IN TXT "model=LaserJet" "duplex"`,
+		`printer.local. 120 IN TXT "location=lab"`,
+	})
+	client := NewClient(Options{MulticastWindow: 200 * time.Millisecond})
+
+	records, err := client.LookupList(context.Background(), "mdns:printer.local", "model")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if expected := []string{"LaserJetduplex"}; !reflect.DeepEqual(records, expected) {
+		t.Error("Expected", expected, "but got", records)
+	}
+
+	provider, err := NewClient(Options{Syntax: SyntaxRFC6763}).Provider("mdns:printer.local?window=100ms")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if window := provider.(*mdnsProvider).resolver.window; window != 100*time.Millisecond {
+		t.Error("Expected 100ms but got", window)
+	}
+	records, err = provider.TxtRecords(context.Background())
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	sort.Strings(records)
+	if expected := []string{"duplex", "location=lab", "model=LaserJet"}; !reflect.DeepEqual(records, expected) {
+		t.Error("Expected", expected, "but got", records)
+	}
+
+	if _, err := client.Table(context.Background(), "mdns:scanner.local"); !errors.Is(err, ErrNoRecords) {
+		t.Error("Expected ErrNoRecords but got", err)
+	}
+	for _, source := range []string{"mdns:printer.local?window=soon", "mdns://host/printer.local", "mdns:"} {
+		if _, err := client.Provider(source); err == nil {
+			t.Error("Expected error for", source)
+		}
+	}
+}
+
+func TestMulticastBrowse(t *testing.T) {
+	useTestResponder(t, []string{
+		`_http._tcp.local. 120 IN PTR lab._http._tcp.local.`,
+		`lab._http._tcp.local. 120 IN SRV 0 0 8080 lab-server.local.`,
+		`lab._http._tcp.local. 120 IN TXT "path=/config"`,
+	})
+	client := NewClient(Options{MulticastWindow: 100 * time.Millisecond})
+
+	instances, err := client.Browse(context.Background(), "_http._tcp.local")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if len(instances) != 1 {
+		t.Fatal("Expected 1 instance but got", len(instances))
+	}
+	if expected := []ServiceTarget{{"lab-server.local.", 8080, 0, 0}}; !reflect.DeepEqual(instances[0].Targets, expected) {
+		t.Error("Expected", expected, "but got", instances[0].Targets)
+	}
+	if value, err := instances[0].Table.Lookup("path"); err != nil || value != "/config" {
+		t.Error("Expected /config but got", value, err)
+	}
+}
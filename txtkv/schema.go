@@ -0,0 +1,290 @@
+package txtkv
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a subset of JSON Schema for checking the keys and values of a table.  The top level describes the whole
+// table, like an "object", and each property describes a key:
+//
+//	{
+//	  "required": ["replicas", "env"],
+//	  "additionalProperties": false,
+//	  "properties": {
+//	    "replicas": {"type": "integer", "minimum": 1},
+//	    "env": {"enum": ["prod", "staging"]},
+//	    "timeout": {"type": "string", "format": "duration"},
+//	    "servers": {"type": "array", "items": {"pattern": "^[a-z0-9.-]+$"}, "minItems": 1}
+//	  }
+//	}
+//
+// Keys with any type but "array" must have exactly one value.  Types are "string", "integer", "number", "boolean"
+// and "array", and formats are "duration", "base64", "hex", "json" and "url" (or "uri").  Patterns are unanchored, as
+// in JSON Schema.  Keys are case-insensitive, and chunked values are reassembled.  Unsupported keywords are errors,
+// except for annotations like "title" and "description".
+type Schema struct {
+	Type                 string             `json:"type"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Format               string             `json:"format"`
+	Enum                 []interface{}      `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+
+	// Annotations, which are ignored
+	SchemaURI   string        `json:"$schema"`
+	ID          string        `json:"$id"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Default     interface{}   `json:"default"`
+	Examples    []interface{} `json:"examples"`
+
+	pattern *regexp.Regexp
+}
+
+var schemaFormats = map[string]func(value string) error{
+	"duration": func(value string) error {
+		_, err := time.ParseDuration(value)
+		return err
+	},
+	"base64": func(value string) error {
+		_, err := base64.StdEncoding.DecodeString(value)
+		return err
+	},
+	"hex": func(value string) error {
+		_, err := hex.DecodeString(value)
+		return err
+	},
+	"json": func(value string) error {
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("invalid JSON")
+		}
+		return nil
+	},
+	"url": func(value string) error {
+		u, err := url.Parse(value)
+		if err == nil && !u.IsAbs() {
+			err = fmt.Errorf("not an absolute URL")
+		}
+		return err
+	},
+}
+
+func init() {
+	schemaFormats["uri"] = schemaFormats["url"]
+}
+
+// ParseSchema parses a schema in JSON form.
+func ParseSchema(data []byte) (*Schema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var schema Schema
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, fmt.Errorf("error parsing schema: %w", err)
+	}
+	if schema.Type != "" && schema.Type != "object" {
+		return nil, fmt.Errorf("unsupported schema type \"%s\" for the record set: only \"object\" is supported", schema.Type)
+	}
+	if schema.Items != nil || schema.MinItems != nil || schema.MaxItems != nil || schema.Format != "" || schema.Enum != nil || schema.Pattern != "" || schema.Minimum != nil || schema.Maximum != nil {
+		return nil, fmt.Errorf("the top level of a schema can only have \"required\", \"properties\" and \"additionalProperties\"")
+	}
+	properties := make(map[string]*Schema, len(schema.Properties))
+	for key, property := range schema.Properties {
+		if property == nil {
+			return nil, fmt.Errorf("invalid schema for key %s", key)
+		}
+		if err := property.compile(key, true); err != nil {
+			return nil, err
+		}
+		properties[strings.ToLower(key)] = property
+	}
+	schema.Properties = properties
+	for i, key := range schema.Required {
+		schema.Required[i] = strings.ToLower(key)
+	}
+	return &schema, nil
+}
+
+func (s *Schema) compile(key string, allowArray bool) error {
+	if s.Required != nil || s.Properties != nil || s.AdditionalProperties != nil {
+		return fmt.Errorf("invalid schema for key %s: values can't be objects", key)
+	}
+	switch s.Type {
+	case "", "string", "integer", "number", "boolean":
+		if s.Items != nil || s.MinItems != nil || s.MaxItems != nil {
+			return fmt.Errorf("invalid schema for key %s: \"items\", \"minItems\" and \"maxItems\" need type \"array\"", key)
+		}
+	case "array":
+		if !allowArray {
+			return fmt.Errorf("invalid schema for key %s: items can't be arrays", key)
+		}
+		if s.Format != "" || s.Enum != nil || s.Pattern != "" || s.Minimum != nil || s.Maximum != nil {
+			return fmt.Errorf("invalid schema for key %s: put value constraints in \"items\" for type \"array\"", key)
+		}
+		if s.Items != nil {
+			return s.Items.compile(key, false)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid schema for key %s: unsupported type \"%s\"", key, s.Type)
+	}
+	if _, ok := schemaFormats[s.Format]; s.Format != "" && !ok {
+		return fmt.Errorf("invalid schema for key %s: unsupported format \"%s\"", key, s.Format)
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid schema for key %s: %w", key, err)
+		}
+		s.pattern = pattern
+	}
+	return nil
+}
+
+// Validate checks a table against a schema, and also checks that all chunked values can be reassembled.  A Schema
+// with no fields only does the chunk checks.  Errors are *SchemaError, with all the problems found.
+func (s *Schema) Validate(table *Table) error {
+	var problems []string
+	keys := table.LogicalKeys()
+	present := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		present[key] = true
+	}
+	for _, key := range s.Required {
+		if !present[key] {
+			problems = append(problems, fmt.Sprintf("required key %s is missing", key))
+		}
+	}
+
+	for _, key := range keys {
+		property := s.Properties[key]
+		if property == nil && s.AdditionalProperties != nil && !*s.AdditionalProperties {
+			problems = append(problems, fmt.Sprintf("key %s is not allowed by the schema", key))
+			continue
+		}
+		value, isChunked, err := table.ReassembleChunks(key)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if property == nil {
+			continue
+		}
+		values := table.LookupList(key)
+		if isChunked {
+			values = []string{value}
+		}
+		problems = append(problems, property.check(key, values)...)
+	}
+
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+func (s *Schema) check(key string, values []string) []string {
+	if s.Type == "array" {
+		var problems []string
+		if s.MinItems != nil && len(values) < *s.MinItems {
+			problems = append(problems, fmt.Sprintf("key %s has %d values, but at least %d are required", key, len(values), *s.MinItems))
+		}
+		if s.MaxItems != nil && len(values) > *s.MaxItems {
+			problems = append(problems, fmt.Sprintf("key %s has %d values, but at most %d are allowed", key, len(values), *s.MaxItems))
+		}
+		if s.Items != nil {
+			for _, value := range values {
+				problems = append(problems, s.Items.checkValue(key, value)...)
+			}
+		}
+		return problems
+	}
+
+	switch len(values) {
+	case 0:
+		// A DNS-SD boolean attribute, which is true just by being there
+		if s.Type == "boolean" {
+			return nil
+		}
+		return []string{fmt.Sprintf("key %s has no value", key)}
+	case 1:
+		return s.checkValue(key, values[0])
+	default:
+		return []string{fmt.Sprintf("key %s has %d values, but only 1 is allowed (use type \"array\" for lists)", key, len(values))}
+	}
+}
+
+func (s *Schema) checkValue(key string, value string) []string {
+	var problems []string
+	invalid := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("value \"%s\" of key %s ", value, key)+fmt.Sprintf(format, args...))
+	}
+
+	var number float64
+	isNumber := false
+	switch s.Type {
+	case "integer":
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			invalid("is not an integer")
+			return problems
+		}
+		number, isNumber = float64(i), true
+	case "number":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			invalid("is not a number")
+			return problems
+		}
+		number, isNumber = f, true
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			invalid("is not a boolean")
+			return problems
+		}
+	}
+	if isNumber && s.Minimum != nil && number < *s.Minimum {
+		invalid("is less than the minimum %v", *s.Minimum)
+	}
+	if isNumber && s.Maximum != nil && number > *s.Maximum {
+		invalid("is more than the maximum %v", *s.Maximum)
+	}
+
+	if s.Format != "" {
+		if err := schemaFormats[s.Format](value); err != nil {
+			invalid("is not a valid %s", s.Format)
+		}
+	}
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		invalid("doesn't match the pattern %s", s.Pattern)
+	}
+	if s.Enum != nil {
+		allowed := make([]string, len(s.Enum))
+		found := false
+		for i, option := range s.Enum {
+			allowed[i] = fmt.Sprint(option)
+			if allowed[i] == value {
+				found = true
+			}
+		}
+		if !found {
+			invalid("is not one of %s", strings.Join(allowed, ", "))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,113 @@
+package txtkv
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type parseSchemaTestPair struct {
+	Schema string
+	Err    error
+}
+
+func TestParseSchema(t *testing.T) {
+	for _, testPair := range []parseSchemaTestPair{
+		{`{}`, nil},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "Service", "type": "object"}`, nil},
+		{`{"properties": {"replicas": {"type": "integer", "minimum": 1, "description": "Number of replicas"}}}`, nil},
+		{`{"properties": {"servers": {"type": "array", "items": {"pattern": "^[a-z]+$"}, "minItems": 1}}}`, nil},
+		{`{"properties": {"timeout": {"format": "duration"}}}`, nil},
+		{`not json`, errors.New("Invalid JSON")},
+		{`{"type": "array"}`, errors.New("Top level not an object")},
+		{`{"pattern": "x"}`, errors.New("Value constraint at top level")},
+		{`{"properties": {"a": {"type": "object"}}}`, errors.New("Nested object")},
+		{`{"properties": {"a": {"properties": {}}}}`, errors.New("Nested properties")},
+		{`{"properties": {"a": {"type": "array", "items": {"type": "array"}}}}`, errors.New("Nested array")},
+		{`{"properties": {"a": {"type": "array", "pattern": "x"}}}`, errors.New("Pattern on array")},
+		{`{"properties": {"a": {"type": "string", "items": {}}}}`, errors.New("Items on string")},
+		{`{"properties": {"a": {"format": "email"}}}`, errors.New("Unsupported format")},
+		{`{"properties": {"a": {"pattern": "("}}}`, errors.New("Invalid pattern")},
+		{`{"properties": {"a": {"maxLength": 3}}}`, errors.New("Unsupported keyword")},
+		{`{"properties": {"a": null}}`, errors.New("Null property")},
+	} {
+		_, err := ParseSchema([]byte(testPair.Schema))
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+	}
+}
+
+type validateTestPair struct {
+	Records  []string
+	Problems []string
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{
+  "required": ["Replicas", "env"],
+  "additionalProperties": false,
+  "properties": {
+    "replicas": {"type": "integer", "minimum": 1, "maximum": 10},
+    "ratio": {"type": "number"},
+    "debug": {"type": "boolean"},
+    "env": {"enum": ["prod", "staging"]},
+    "timeout": {"format": "duration"},
+    "name": {"type": "string", "pattern": "^[a-z]+$"},
+    "cert": {"format": "base64"},
+    "servers": {"type": "array", "items": {"pattern": "\\.example\\.com$"}, "minItems": 1, "maxItems": 2}
+  }
+}`))
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+
+	for _, testPair := range []validateTestPair{
+		{[]string{"replicas=3", "env=prod"}, nil},
+		{[]string{"replicas=3", "env=prod", "ratio=0.5", "debug=true", "timeout=1m", "name=web", "cert#0=aGVs", "cert#1=bG8=", "cert#n=2", "servers=a.example.com", "servers=b.example.com", "sdget-include=dns:other.example.com"}, nil},
+		{[]string{"env=prod"}, []string{"required key replicas is missing"}},
+		{[]string{"replicas=three", "env=prod"}, []string{"value \"three\" of key replicas is not an integer"}},
+		{[]string{"replicas=0", "env=prod"}, []string{"value \"0\" of key replicas is less than the minimum 1"}},
+		{[]string{"replicas=11", "env=prod"}, []string{"value \"11\" of key replicas is more than the maximum 10"}},
+		{[]string{"replicas=1", "replicas=2", "env=prod"}, []string{"key replicas has 2 values, but only 1 is allowed (use type \"array\" for lists)"}},
+		{[]string{"replicas=1", "env=dev"}, []string{"value \"dev\" of key env is not one of prod, staging"}},
+		{[]string{"replicas=1", "env=prod", "ratio=NaN", "debug=maybe"}, []string{"value \"maybe\" of key debug is not a boolean", "value \"NaN\" of key ratio is not a number"}},
+		{[]string{"replicas=1", "env=prod", "timeout=soon", "name=Web"}, []string{"value \"Web\" of key name doesn't match the pattern ^[a-z]+$", "value \"soon\" of key timeout is not a valid duration"}},
+		{[]string{"replicas=1", "env=prod", "servers=a.example.org"}, []string{"value \"a.example.org\" of key servers doesn't match the pattern \\.example\\.com$"}},
+		{[]string{"replicas=1", "env=prod", "servers=a.example.com", "servers=b.example.com", "servers=c.example.com"}, []string{"key servers has 3 values, but at most 2 are allowed"}},
+		{[]string{"replicas=1", "env=prod", "cert#0=aGVs", "cert#2=bG8=", "cert#n=2"}, []string{"invalid chunked value: chunk 1 of key cert is missing"}},
+		{[]string{"replicas=1", "env=prod", "colour=blue"}, []string{"key colour is not allowed by the schema"}},
+	} {
+		var problems []string
+		if err := schema.Validate(ParseRecords(testPair.Records)); err != nil {
+			schemaErr, ok := err.(*SchemaError)
+			if !ok {
+				t.Error("Expected *SchemaError but got", err, "for", testPair)
+				continue
+			}
+			problems = schemaErr.Problems
+		}
+
+		if !reflect.DeepEqual(problems, testPair.Problems) {
+			t.Error("Expected", testPair.Problems, "but got", problems, "for", testPair)
+		}
+	}
+
+	attributes := ParseAttributes([]string{"replicas=1", "env=prod", "debug"})
+	if err := schema.Validate(attributes); err != nil {
+		t.Error("Unexpected error", err.Error(), "for boolean attribute")
+	}
+	attributes = ParseAttributes([]string{"replicas", "env=prod"})
+	if err := schema.Validate(attributes); err == nil {
+		t.Error("Expected error for integer key with no value")
+	}
+
+	if err := (&Schema{}).Validate(ParseRecords([]string{"anything=goes", "cert#1=x", "cert#n=1"})); err == nil {
+		t.Error("Expected error for invalid chunks with an empty schema")
+	}
+}
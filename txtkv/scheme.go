@@ -0,0 +1,143 @@
+package txtkv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Component is a set of optional URI components.
+type Component int
+
+// URI components that a Scheme can accept.  The scheme and path are always allowed.
+const (
+	ComponentAuthority Component = 1 << iota
+	ComponentQuery
+	ComponentFragment
+)
+
+// Scheme creates Providers for source URIs with a particular scheme.
+//
+// Sources are validated against the scheme before New is called: URIs with components that aren't in Components, or
+// with query parameters that aren't in Params, are rejected.
+type Scheme struct {
+	// Components are the optional URI components the scheme accepts.
+	Components Component
+
+	// Params are the query parameter names (in lower case) the scheme accepts.  ComponentQuery is needed as well.
+	Params []string
+
+	// New creates a Provider for a validated URI.
+	New func(options *Options, uri *URI) (Provider, error)
+
+	// Network is whether the records come from the network (like DNS), rather than from the local machine (like files).
+	// Records from the network can only reference other network sources, so that whoever publishes them can't read
+	// local files through interpolation or includes.
+	Network bool
+}
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = make(map[string]Scheme)
+)
+
+// RegisterScheme makes a URI scheme available to all Clients.  It panics if the scheme name is invalid or already
+// registered, so it's typically called from an init function.
+func RegisterScheme(name string, scheme Scheme) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	if !parser.MatchString(name + ":") {
+		panic(fmt.Sprintf("txtkv: invalid URI scheme name \"%s\"", name))
+	}
+	if scheme.New == nil {
+		panic(fmt.Sprintf("txtkv: URI scheme %s registered without a New function", name))
+	}
+	if _, ok := schemes[name]; ok {
+		panic(fmt.Sprintf("txtkv: URI scheme %s registered twice", name))
+	}
+	schemes[name] = scheme
+}
+
+// Schemes returns the names of all registered URI schemes, sorted.
+func Schemes() []string {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	var names []string
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookUpScheme(name string) (Scheme, bool) {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	scheme, ok := schemes[name]
+	return scheme, ok
+}
+
+func (s *Scheme) validate(uri *URI) error {
+	if uri.Authority != "" && s.Components&ComponentAuthority == 0 {
+		return fmt.Errorf("unexpected \"%s\": authorities in %s URIs not supported", uri.Authority, uri.Scheme)
+	}
+	if uri.RawQuery != "" && s.Components&ComponentQuery == 0 {
+		return fmt.Errorf("unexpected \"?%s\": queries in %s URIs not supported", uri.RawQuery, uri.Scheme)
+	}
+	if uri.Fragment != "" && s.Components&ComponentFragment == 0 {
+		return fmt.Errorf("unexpected \"#%s\": fragments in %s URIs not supported", uri.Fragment, uri.Scheme)
+	}
+	var unknown []string
+	for name := range uri.Params {
+		if !containsString(s.Params, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unsupported query parameter(s) in %s URI: %s", uri.Scheme, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// param returns the last value of a query parameter, so that later values override earlier ones.
+func (u *URI) param(name string) (string, bool) {
+	values, ok := u.Params[name]
+	if !ok {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// isNetworkSource is whether the records of a source come from the network.  Plain domain names are DNS sources, and
+// the empty source (for values given by the caller, like defaults) is local.
+func isNetworkSource(source string) bool {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return false
+	}
+	i := strings.IndexByte(source, ':')
+	if i < 0 {
+		return true
+	}
+	scheme, ok := lookUpScheme(strings.ToLower(source[:i]))
+	return ok && scheme.Network
+}
+
+// checkReference stops records from a network source from pulling in the records of a local source
+func checkReference(from string, to string) error {
+	if isNetworkSource(from) && !isNetworkSource(to) {
+		return fmt.Errorf("%w: %s can't reference %s", ErrLocalReference, from, to)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package txtkv
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type staticProvider []string
+
+func (p staticProvider) TxtRecords(ctx context.Context) ([]string, error) {
+	return p, nil
+}
+
+func init() {
+	RegisterScheme("test", Scheme{
+		Components: ComponentQuery,
+		Params:     []string{"value"},
+		New: func(options *Options, uri *URI) (Provider, error) {
+			value, _ := uri.param("value")
+			return staticProvider{uri.Path + "=" + value}, nil
+		},
+	})
+}
+
+type schemeTestPair struct {
+	Source string
+	Err    error
+}
+
+func TestSchemeValidation(t *testing.T) {
+	client := NewClient(Options{})
+	for _, testPair := range []schemeTestPair{
+		{"test:foo", nil},
+		{"test:foo?value=bar", nil},
+		{"test://authority/foo", errors.New("Authority not allowed")},
+		{"test:foo?other=bar", errors.New("Unknown parameter")},
+		{"test:foo#fragment", errors.New("Fragment not allowed")},
+		{"file:/tmp/records?value=bar", errors.New("Unknown parameter")},
+		{"file:/tmp/records?format=yaml", nil},
+		{"file:/tmp/records?format=xml", errors.New("Invalid format")},
+		{"dns://127.0.0.1/foo.example.com?type=TXT;CLASS=in&timeout=2s&dnssec", nil},
+		{"dns://127.0.0.1/foo.example.com?type=A", errors.New("Unsupported type")},
+		{"dns://127.0.0.1/foo.example.com?class=CH", errors.New("Unsupported class")},
+		{"dns://127.0.0.1/foo.example.com?timeout=soon", errors.New("Invalid timeout")},
+		{"dns://127.0.0.1/foo.example.com?timeout=-1s", errors.New("Invalid timeout")},
+		{"dns://127.0.0.1/foo.example.com?dnssec=maybe", errors.New("Invalid dnssec")},
+		{"dns://127.0.0.1/foo.example.com#fragment", errors.New("Fragment not allowed")},
+	} {
+		_, err := client.Provider(testPair.Source)
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+	}
+
+	value, err := client.Lookup(context.Background(), "test:foo?value=bar", "foo")
+	if err != nil || value != "bar" {
+		t.Error("Expected bar but got", value, err)
+	}
+
+	if schemes, expected := Schemes(), []string{"dns", "file", "mdns", "snapshot", "test"}; !reflect.DeepEqual(schemes, expected) {
+		t.Error("Expected", expected, "but got", schemes)
+	}
+}
+
+func TestDnsURIParams(t *testing.T) {
+	server := startTestServer(t, "127.0.0.1:0", []string{
+		`foo.example.com. 300 IN TXT "key=value"`,
+	})
+	client := NewClient(Options{})
+
+	provider, err := client.Provider("dns://" + server + "/foo.example.com?timeout=5s")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if timeout := provider.(*dnsProvider).timeout; timeout != 5*time.Second {
+		t.Error("Expected 5s timeout but got", timeout)
+	}
+	if _, err := provider.TxtRecords(context.Background()); err != nil {
+		t.Error("Error", err.Error())
+	}
+
+	// The test server doesn't validate anything
+	provider, err = client.Provider("dns://" + server + "/foo.example.com?dnssec=1")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if _, err := provider.TxtRecords(context.Background()); err == nil {
+		t.Error("Expected error for unauthenticated response")
+	}
+
+	// Relative names are under Options.BaseDomain
+	baseClient := NewClient(Options{BaseDomain: "example.com"})
+	if value, err := baseClient.Lookup(context.Background(), "dns://"+server+"/foo", "key"); err != nil || value != "value" {
+		t.Error("Expected value but got", value, err)
+	}
+	if value, err := baseClient.Lookup(context.Background(), "dns://"+server+"/foo.example.com.", "key"); err != nil || value != "value" {
+		t.Error("Expected value but got", value, err)
+	}
+
+	// Options.DNSSEC is the default, but the parameter can turn it off
+	dnssecClient := NewClient(Options{DNSSEC: true})
+	provider, err = dnssecClient.Provider("dns://" + server + "/foo.example.com")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if _, err := provider.TxtRecords(context.Background()); err == nil {
+		t.Error("Expected error for unauthenticated response")
+	}
+	provider, err = dnssecClient.Provider("dns://" + server + "/foo.example.com?dnssec=0")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if _, err := provider.TxtRecords(context.Background()); err != nil {
+		t.Error("Error", err.Error())
+	}
+
+	iterativeClient := NewClient(Options{Resolve: ResolveIterative})
+	if _, err := iterativeClient.Provider("dns:foo.example.com?dnssec=1"); err == nil {
+		t.Error("Expected error for DNSSEC with iterative resolution")
+	}
+}
@@ -0,0 +1,205 @@
+package txtkv
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// SignatureKey is the reserved key for Ed25519 signatures of a source's records.  Its values look like
+// "ed25519:<key ID>:<inception>:<expiration>:<base64 signature>", with the times as YYYYMMDDHHMMSS in UTC.  A source
+// can have signatures from several keys (e.g., while rotating keys).
+const SignatureKey = "sdget-signature"
+
+const signatureAlgorithm = "ed25519"
+
+// KeyID identifies a public key in signature records.  It's the first 8 bytes of the key's SHA-256 hash, in hex.
+func KeyID(key ed25519.PublicKey) string {
+	hash := sha256.Sum256(key)
+	return hex.EncodeToString(hash[:8])
+}
+
+// ParsePublicKeys parses all the Ed25519 public keys in PEM data ("PUBLIC KEY" blocks, like from
+// "openssl pkey -pubout").
+func ParsePublicKeys(data []byte) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing public key: %w", err)
+		}
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported public key type %T: only Ed25519 is supported", key)
+		}
+		keys = append(keys, publicKey)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no PEM-encoded public keys found")
+	}
+	return keys, nil
+}
+
+// ParsePrivateKey parses an Ed25519 private key in PEM-encoded PKCS #8 form ("PRIVATE KEY", like from
+// "openssl genpkey -algorithm ed25519").
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no PEM-encoded private key found")
+		}
+		if block.Type != "PRIVATE KEY" {
+			continue
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing private key: %w", err)
+		}
+		privateKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T: only Ed25519 is supported", key)
+		}
+		return privateKey, nil
+	}
+}
+
+// signatureTimeFormat is the format of signature inception and expiration times (in UTC), like in RRSIG records
+const signatureTimeFormat = "20060102150405"
+
+// SignTable returns a signature record for all the key/value pairs in a table (except signatures), as published under
+// a DNS name.  The signature is only valid from inception until expiration.
+func SignTable(table *Table, key ed25519.PrivateKey, name string, inception time.Time, expiration time.Time) string {
+	inceptionText := inception.UTC().Format(signatureTimeFormat)
+	expirationText := expiration.UTC().Format(signatureTimeFormat)
+	signature := ed25519.Sign(key, signedMessage(table, name, inceptionText, expirationText))
+	value := strings.Join([]string{
+		signatureAlgorithm,
+		KeyID(key.Public().(ed25519.PublicKey)),
+		inceptionText,
+		expirationText,
+		base64.StdEncoding.EncodeToString(signature),
+	}, ":")
+	return EncodeRecord(SignatureKey, value)
+}
+
+// VerifyTable checks that a table published under a DNS name has a signature from at least one of the trusted keys,
+// made for that name and valid at the given time.  Signatures from other keys are ignored.  Errors match
+// ErrBadSignature.
+func VerifyTable(table *Table, keys []ed25519.PublicKey, name string, now time.Time) error {
+	signatures := table.LookupList(SignatureKey)
+	if len(signatures) == 0 {
+		return fmt.Errorf("%w: no %s records", ErrBadSignature, SignatureKey)
+	}
+	var lastErr error
+	for _, value := range signatures {
+		parts := strings.Split(value, ":")
+		if len(parts) != 5 || parts[0] != signatureAlgorithm {
+			continue
+		}
+		keyID, inceptionText, expirationText := parts[1], parts[2], parts[3]
+		for _, key := range keys {
+			if KeyID(key) != keyID {
+				continue
+			}
+			signature, err := base64.StdEncoding.DecodeString(parts[4])
+			if err != nil || !ed25519.Verify(key, signedMessage(table, name, inceptionText, expirationText), signature) {
+				lastErr = fmt.Errorf("%w: invalid signature for %s from key %s", ErrBadSignature, signedName(name), keyID)
+				continue
+			}
+			// The times are signed, so they can be trusted now
+			inception, err := time.Parse(signatureTimeFormat, inceptionText)
+			if err != nil {
+				lastErr = fmt.Errorf("%w: invalid inception time %s", ErrBadSignature, inceptionText)
+				continue
+			}
+			expiration, err := time.Parse(signatureTimeFormat, expirationText)
+			if err != nil {
+				lastErr = fmt.Errorf("%w: invalid expiration time %s", ErrBadSignature, expirationText)
+				continue
+			}
+			if now.Before(inception) {
+				lastErr = fmt.Errorf("%w: signature from key %s isn't valid until %s", ErrBadSignature, keyID, inception.Format(time.RFC3339))
+				continue
+			}
+			if now.After(expiration) {
+				lastErr = fmt.Errorf("%w: signature from key %s expired at %s", ErrBadSignature, keyID, expiration.Format(time.RFC3339))
+				continue
+			}
+			return nil
+		}
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+	return fmt.Errorf("%w: no signatures from trusted keys", ErrBadSignature)
+}
+
+// Names are compared as lower case FQDNs, the same as in DNS
+func signedName(name string) string {
+	return strings.ToLower(dns.Fqdn(name))
+}
+
+// The signed message starts with the name and validity period, so that records can't be replayed under another name
+// or after they've expired
+func signedMessage(table *Table, name string, inception string, expiration string) []byte {
+	header := fmt.Sprintf("name %s\ninception %s\nexpiration %s\n", strconv.Quote(signedName(name)), inception, expiration)
+	return append([]byte(header), canonicalRecords(table)...)
+}
+
+// The signed message is every key/value pair, one per line, sorted.  Keys and values are quoted so that there's no
+// ambiguity.  Keys with no values (DNS-SD boolean attributes) are just the quoted key.
+func canonicalRecords(table *Table) []byte {
+	var lines []string
+	for _, key := range table.keys {
+		if key == SignatureKey {
+			continue
+		}
+		if len(table.values[key]) == 0 {
+			lines = append(lines, strconv.Quote(key))
+		}
+		for _, value := range table.values[key] {
+			lines = append(lines, strconv.Quote(key)+" "+strconv.Quote(value))
+		}
+	}
+	sort.Strings(lines)
+	var builder strings.Builder
+	for _, line := range lines {
+		builder.WriteString(line)
+		builder.WriteByte('\n')
+	}
+	return []byte(builder.String())
+}
+
+// Providers with records published under a DNS name say what it is, so that signatures can be checked against it
+type namedProvider interface {
+	recordName() string
+}
+
+// RecordName returns the DNS name that a provider's records are published under (like the domain of DNS sources, or
+// the name parameter of file sources), or "" if it doesn't have one.
+func RecordName(provider Provider) string {
+	if named, ok := provider.(namedProvider); ok {
+		return named.recordName()
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package txtkv
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func generateTestKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	return publicKey, privateKey
+}
+
+func TestParseKeys(t *testing.T) {
+	publicKey1, privateKey := generateTestKey(t)
+	publicKey2, _ := generateTestKey(t)
+
+	var publicPEM []byte
+	for _, key := range []ed25519.PublicKey{publicKey1, publicKey2} {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal("Error", err.Error())
+		}
+		publicPEM = append(publicPEM, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+	}
+	keys, err := ParsePublicKeys(publicPEM)
+	if err != nil || len(keys) != 2 || !keys[0].Equal(publicKey1) || !keys[1].Equal(publicKey2) {
+		t.Error("Expected 2 public keys but got", keys, err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	parsed, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil || !parsed.Equal(privateKey) {
+		t.Error("Expected private key but got", parsed, err)
+	}
+
+	if _, err := ParsePublicKeys([]byte("not PEM")); err == nil {
+		t.Error("Expected error for data without public keys")
+	}
+	if _, err := ParsePrivateKey(publicPEM); err == nil {
+		t.Error("Expected error for data without a private key")
+	}
+}
+
+func TestVerifyTable(t *testing.T) {
+	oldPublicKey, oldPrivateKey := generateTestKey(t)
+	newPublicKey, newPrivateKey := generateTestKey(t)
+	_, otherPrivateKey := generateTestKey(t)
+	records := []string{"deploy=on", "list=b", "list=a"}
+	table := ParseRecords(records)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	inception, expiration := now.Add(-time.Hour), now.Add(24*time.Hour)
+	sign := func(key ed25519.PrivateKey) string {
+		return SignTable(table, key, "foo.example.com", inception, expiration)
+	}
+
+	signed := ParseRecords(append(records, sign(newPrivateKey)))
+	if err := VerifyTable(signed, []ed25519.PublicKey{newPublicKey}, "foo.example.com", now); err != nil {
+		t.Error("Error", err.Error())
+	}
+
+	// Names are compared like DNS names
+	if err := VerifyTable(signed, []ed25519.PublicKey{newPublicKey}, "FOO.example.com.", now); err != nil {
+		t.Error("Error", err.Error())
+	}
+
+	// Record order doesn't matter, and neither do other signatures
+	reordered := ParseRecords([]string{sign(otherPrivateKey), "list=a", "deploy=on", sign(newPrivateKey), "list=b"})
+	if err := VerifyTable(reordered, []ed25519.PublicKey{newPublicKey}, "foo.example.com", now); err != nil {
+		t.Error("Error", err.Error())
+	}
+
+	// Rotation: either trusted key is enough
+	rotating := ParseRecords(append(records, sign(oldPrivateKey)))
+	if err := VerifyTable(rotating, []ed25519.PublicKey{oldPublicKey, newPublicKey}, "foo.example.com", now); err != nil {
+		t.Error("Error", err.Error())
+	}
+
+	for _, tampered := range [][]string{
+		{"deploy=off", "list=b", "list=a", sign(newPrivateKey)},
+		{"deploy=on", "list=b", sign(newPrivateKey)},
+		{"deploy=on", "list=b", "list=a", "extra=1", sign(newPrivateKey)},
+		{"deploy=on", "list=b", "list=a", sign(otherPrivateKey)},
+		{"deploy=on", "list=b", "list=a", SignatureKey + "=ed25519:" + KeyID(newPublicKey) + ":20240101000000:20250101000000:bm90IGEgc2lnbmF0dXJl"},
+		{"deploy=on", "list=b", "list=a", strings.Replace(sign(newPrivateKey), expiration.Format("20060102150405"), "20991231000000", 1)},
+		{"deploy=on", "list=b", "list=a"},
+	} {
+		err := VerifyTable(ParseRecords(tampered), []ed25519.PublicKey{newPublicKey}, "foo.example.com", now)
+		if !errors.Is(err, ErrBadSignature) {
+			t.Error("Expected ErrBadSignature but got", err, "for", tampered)
+		}
+	}
+
+	// Signed records can't be used under another name, or outside their validity period
+	for _, check := range []struct {
+		Name string
+		Now  time.Time
+	}{
+		{"staging.example.com", now},
+		{"foo.example.com", inception.Add(-time.Second)},
+		{"foo.example.com", expiration.Add(time.Second)},
+	} {
+		err := VerifyTable(signed, []ed25519.PublicKey{newPublicKey}, check.Name, check.Now)
+		if !errors.Is(err, ErrBadSignature) {
+			t.Error("Expected ErrBadSignature but got", err, "for", check)
+		}
+	}
+}
+
+func TestClientVerify(t *testing.T) {
+	publicKey, privateKey := generateTestKey(t)
+	signature := SignTable(ParseRecords([]string{"deploy=on"}), privateKey, "foo.example.com", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	signedSource := writeTestRecords(t, "deploy=on\n"+signature) + "?name=foo.example.com"
+	tamperedSource := writeTestRecords(t, "deploy=off\n"+signature) + "?name=foo.example.com"
+	otherNameSource := writeTestRecords(t, "deploy=on\n"+signature) + "?name=staging.example.com"
+	unnamedSource := writeTestRecords(t, "deploy=on\n"+signature)
+
+	client := NewClient(Options{VerifyKeys: []ed25519.PublicKey{publicKey}})
+	if value, err := client.Lookup(context.Background(), signedSource, "deploy"); err != nil || value != "on" {
+		t.Error("Expected on but got", value, err)
+	}
+	for _, source := range []string{tamperedSource, otherNameSource, unnamedSource} {
+		_, err := client.Lookup(context.Background(), source, "deploy")
+		if !errors.Is(err, ErrBadSignature) || !strings.Contains(err.Error(), source) {
+			t.Error("Expected ErrBadSignature for", source, "but got", err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package txtkv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// SnapshotVersion is the version of the snapshot file format written by WriteSnapshot.
+const SnapshotVersion = 1
+
+// Snapshot is a saved copy of the TXT records of a source, with where and when they came from, for use where the
+// source can't be reached (like in air-gapped images).  The metadata fields that only make sense for DNS sources are
+// left out for other sources.
+type Snapshot struct {
+	Version int    `json:"version"`
+	Source  string `json:"source"`
+	// Domain is the domain name that was queried.
+	Domain string `json:"domain,omitempty"`
+	// Nameserver is the nameserver that answered, or "iterative" for iterative resolution.
+	Nameserver string    `json:"nameserver,omitempty"`
+	FetchedAt  time.Time `json:"fetched_at"`
+	// TTL is the lowest TTL of the TXT records, in seconds.
+	TTL *uint32 `json:"ttl,omitempty"`
+	// Authenticated is whether the response was authenticated with DNSSEC (the AD bit was set).
+	Authenticated *bool `json:"dnssec_authenticated,omitempty"`
+	// Zone and SOASerial are from the SOA record of the zone the domain is in, if it could be found.
+	Zone      string   `json:"zone,omitempty"`
+	SOASerial *uint32  `json:"soa_serial,omitempty"`
+	Records   []string `json:"records"`
+}
+
+// Providers that know more about where their records came from can fill in the snapshot metadata
+type snapshotter interface {
+	snapshot(ctx context.Context) (*Snapshot, error)
+}
+
+// Snapshot fetches the TXT records of a source (without following includes), along with metadata about the fetch.
+// If Options.VerifyKeys are set, the records must be validly signed, so that snapshots can't capture bad records.
+// A snapshot of a snapshot source is the original snapshot.
+func (c *Client) Snapshot(ctx context.Context, source string) (*Snapshot, error) {
+	ctx = c.traceContext(ctx)
+	traceSource(ctx, source)
+	provider, err := c.Provider(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.acquire(ctx); err != nil {
+		return nil, &FetchError{Source: source, Err: err}
+	}
+	snapshot := &Snapshot{FetchedAt: time.Now().UTC()}
+	if s, ok := provider.(snapshotter); ok {
+		snapshot, err = s.snapshot(ctx)
+	} else {
+		snapshot.Records, err = provider.TxtRecords(ctx)
+	}
+	c.release()
+	if err != nil {
+		tracef(ctx, "error fetching %s: %s", source, err.Error())
+		return nil, &FetchError{Source: source, Err: err}
+	}
+
+	if snapshot.Source == "" {
+		snapshot.Source = source
+	}
+	snapshot.Version = SnapshotVersion
+	if snapshot.Records == nil {
+		snapshot.Records = []string{}
+	}
+	if err := c.verify(parseRecordsFrom(snapshot.Records, source, c.options.Syntax), source, RecordName(provider)); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// ReadSnapshot reads a snapshot file.  Files from newer versions of the format are an error.
+func ReadSnapshot(input io.Reader) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.NewDecoder(input).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("error reading snapshot: %w", err)
+	}
+	if snapshot.Version == 0 {
+		return nil, errors.New("not a snapshot: no version")
+	}
+	if snapshot.Version > SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d (versions up to %d are supported)", snapshot.Version, SnapshotVersion)
+	}
+	return &snapshot, nil
+}
+
+// WriteSnapshot writes a snapshot file, as indented JSON.
+func WriteSnapshot(output io.Writer, snapshot *Snapshot) error {
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snapshot); err != nil {
+		return fmt.Errorf("error writing snapshot: %w", err)
+	}
+	return nil
+}
+
+func (d *dnsProvider) snapshot(ctx context.Context) (*Snapshot, error) {
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+	fetchedAt := time.Now().UTC()
+	response, err := d.query(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records, err := d.txtRecords(response)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticated := response.AuthenticatedData
+	snapshot := &Snapshot{
+		Domain:        d.domain,
+		Nameserver:    d.nameserver,
+		FetchedAt:     fetchedAt,
+		Authenticated: &authenticated,
+		Records:       records,
+	}
+	if d.resolver != nil {
+		snapshot.Nameserver = ResolveIterative
+	}
+	for _, rr := range response.Answer {
+		if txt, ok := rr.(*dns.TXT); ok && (snapshot.TTL == nil || txt.Hdr.Ttl < *snapshot.TTL) {
+			ttl := txt.Hdr.Ttl
+			snapshot.TTL = &ttl
+		}
+	}
+	// The snapshot is still useful without the serial
+	if soa, err := findSOA(ctx, d.resolveFunc(), d.domain); err == nil {
+		snapshot.Zone = soa.Hdr.Name
+		snapshot.SOASerial = &soa.Serial
+	} else {
+		tracef(ctx, "no SOA serial for the snapshot: %s", err.Error())
+	}
+	return snapshot, nil
+}
+
+func (d *dnsProvider) resolveFunc() resolveFunc {
+	if d.resolver != nil {
+		return d.resolver.resolve
+	}
+	return func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+		return recursiveQuery(ctx, d.nameserver, name, qtype)
+	}
+}
+
+// Snapshot sources are read from snapshot files, like "snapshot:///var/lib/sdget/foo.snap?max-age=24h"
+type snapshotProvider struct {
+	options *Options
+	path    string
+	maxAge  time.Duration
+	// name is the DNS name for checking signatures, from the name parameter or the domain of the snapshot
+	name string
+}
+
+func init() {
+	RegisterScheme("snapshot", Scheme{
+		Components: ComponentAuthority | ComponentQuery,
+		Params:     []string{"max-age", "name"},
+		New:        newSnapshotProvider,
+	})
+}
+
+func newSnapshotProvider(options *Options, uri *URI) (Provider, error) {
+	file, err := makeFileProvider(options, uri.Authority, uri.Path)
+	if err != nil {
+		return nil, err
+	}
+	provider := &snapshotProvider{
+		options: options,
+		path:    file.path,
+		maxAge:  options.MaxSnapshotAge,
+	}
+	provider.name, _ = uri.param("name")
+	if maxAge, ok := uri.param("max-age"); ok {
+		provider.maxAge, err = time.ParseDuration(maxAge)
+		if err != nil || provider.maxAge <= 0 {
+			return nil, fmt.Errorf("invalid max-age \"%s\": must be a positive duration like 24h", maxAge)
+		}
+	}
+	return provider, nil
+}
+
+func (s *snapshotProvider) TxtRecords(ctx context.Context) ([]string, error) {
+	snapshot, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Records, nil
+}
+
+func (s *snapshotProvider) snapshot(ctx context.Context) (*Snapshot, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("%w for snapshot: %w", ErrFileAccess, err)
+	}
+	defer file.Close()
+	snapshot, err := ReadSnapshot(file)
+	if err != nil {
+		return nil, fmt.Errorf("error in %s: %w", s.path, err)
+	}
+	tracef(ctx, "snapshot of %s, fetched at %s", snapshot.Source, snapshot.FetchedAt.Format(time.RFC3339))
+	if s.name == "" {
+		s.name = snapshot.Domain
+	}
+
+	age := time.Since(snapshot.FetchedAt)
+	if s.maxAge > 0 && age > s.maxAge {
+		return nil, fmt.Errorf("%w: %s was fetched %s ago (max age %s)", ErrStaleSnapshot, s.path, age.Round(time.Second), s.maxAge)
+	}
+	return snapshot, nil
+}
+
+func (s *snapshotProvider) recordName() string {
+	return s.name
+}
@@ -0,0 +1,146 @@
+package txtkv
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestSnapshot(t *testing.T, snapshot *Snapshot) string {
+	path := filepath.Join(t.TempDir(), "test.snap")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	defer file.Close()
+	if err := WriteSnapshot(file, snapshot); err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	return path
+}
+
+func TestSnapshot(t *testing.T) {
+	server := startTestServer(t, "127.0.0.1:0", []string{
+		"example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 2019010302 3600 600 86400 300",
+		`example.com. 300 IN TXT "foo=bar"`,
+		`example.com. 60 IN TXT "port=8080"`,
+	})
+	ctx := context.Background()
+	client := NewClient(Options{})
+	source := "dns://" + server + "/example.com"
+
+	snapshot, err := client.Snapshot(ctx, source)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	sort.Strings(snapshot.Records)
+	if !reflect.DeepEqual(snapshot.Records, []string{"foo=bar", "port=8080"}) {
+		t.Error("Expected foo=bar and port=8080 but got", snapshot.Records)
+	}
+	if snapshot.Version != SnapshotVersion || snapshot.Source != source || snapshot.Domain != "example.com." || snapshot.Nameserver != server {
+		t.Error("Unexpected snapshot metadata", snapshot)
+	}
+	if snapshot.TTL == nil || *snapshot.TTL != 60 {
+		t.Error("Expected TTL 60 but got", snapshot.TTL)
+	}
+	if snapshot.Authenticated == nil || *snapshot.Authenticated {
+		t.Error("Expected unauthenticated response but got", snapshot.Authenticated)
+	}
+	if snapshot.Zone != "example.com." || snapshot.SOASerial == nil || *snapshot.SOASerial != 2019010302 {
+		t.Error("Expected zone example.com. with serial 2019010302 but got", snapshot.Zone, snapshot.SOASerial)
+	}
+	if age := time.Since(snapshot.FetchedAt); age < 0 || age > time.Minute {
+		t.Error("Unexpected fetch time", snapshot.FetchedAt)
+	}
+
+	// Snapshots can be read back as sources, and snapshots of them are the original snapshot
+	path := writeTestSnapshot(t, snapshot)
+	if value, err := client.Lookup(ctx, "snapshot:"+path, "port"); err != nil || value != "8080" {
+		t.Error("Expected 8080 but got", value, err)
+	}
+	resnapshot, err := client.Snapshot(ctx, "snapshot:"+path)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if resnapshot.Source != source || !resnapshot.FetchedAt.Equal(snapshot.FetchedAt) || *resnapshot.SOASerial != *snapshot.SOASerial {
+		t.Error("Expected", snapshot, "but got", resnapshot)
+	}
+
+	// Other providers only have the records
+	snapshot, err = client.Snapshot(ctx, "test:foo?value=bar")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if !reflect.DeepEqual(snapshot.Records, []string{"foo=bar"}) || snapshot.Domain != "" || snapshot.TTL != nil {
+		t.Error("Unexpected snapshot", snapshot)
+	}
+}
+
+type snapshotMaxAgeTestPair struct {
+	Source         string
+	MaxSnapshotAge time.Duration
+	Err            error
+}
+
+func TestSnapshotMaxAge(t *testing.T) {
+	path := writeTestSnapshot(t, &Snapshot{
+		Version:   SnapshotVersion,
+		Source:    "foo.example.com",
+		FetchedAt: time.Now().Add(-time.Hour),
+		Records:   []string{"foo=bar"},
+	})
+	for _, testPair := range []snapshotMaxAgeTestPair{
+		{"snapshot:" + path, 0, nil},
+		{"snapshot:" + path + "?max-age=2h", 0, nil},
+		{"snapshot:" + path + "?max-age=30m", 0, ErrStaleSnapshot},
+		{"snapshot:" + path, 30 * time.Minute, ErrStaleSnapshot},
+		{"snapshot:" + path + "?max-age=2h", 30 * time.Minute, nil},
+		{"snapshot:" + path + "?max-age=soon", 0, errors.New("Invalid max-age")},
+	} {
+		client := NewClient(Options{MaxSnapshotAge: testPair.MaxSnapshotAge})
+		_, err := client.Lookup(context.Background(), testPair.Source, "foo")
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if testPair.Err == ErrStaleSnapshot && !errors.Is(err, ErrStaleSnapshot) {
+			t.Error("Expected ErrStaleSnapshot but got", err, "for", testPair)
+		}
+	}
+}
+
+type readSnapshotTestPair struct {
+	Input string
+	Err   error
+}
+
+func TestReadSnapshot(t *testing.T) {
+	for _, testPair := range []readSnapshotTestPair{
+		{`{"version": 1, "source": "foo.example.com", "fetched_at": "2024-06-01T00:00:00Z", "records": ["foo=bar"]}`, nil},
+		{`{"source": "foo.example.com", "records": ["foo=bar"]}`, errors.New("No version")},
+		{`{"version": 2, "source": "foo.example.com", "records": ["foo=bar"]}`, errors.New("Newer version")},
+		{`"foo=bar"`, errors.New("Not an object")},
+		{`foo=bar`, errors.New("Not JSON")},
+	} {
+		_, err := ReadSnapshot(strings.NewReader(testPair.Input))
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+	}
+}
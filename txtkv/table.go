@@ -0,0 +1,244 @@
+package txtkv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Layering policies for MergeTables
+const (
+	// LayerFirstWins takes all values of each key from the first table that has the key.
+	LayerFirstWins = "first-wins"
+	// LayerMerge concatenates the values of each key from all tables.
+	LayerMerge = "merge"
+	// LayerRequireAll concatenates the values of each key, but only keeps keys that are in every table.
+	LayerRequireAll = "require-all"
+)
+
+// Record syntaxes for Options.Syntax
+const (
+	// SyntaxRFC1464 is the syntax of SplitRecord: every record is a key/value pair, and repeated keys are lists.
+	SyntaxRFC1464 = "rfc1464"
+	// SyntaxRFC6763 is the DNS-SD syntax of SplitAttribute: keys can be boolean attributes without values, and only
+	// the first occurrence of a key counts.
+	SyntaxRFC6763 = "rfc6763"
+)
+
+// Table is a set of TXT records parsed into key/value pairs.
+type Table struct {
+	keys    []string
+	values  map[string][]string
+	sources map[string][]string
+}
+
+func makeTable() *Table {
+	return &Table{
+		values:  make(map[string][]string),
+		sources: make(map[string][]string),
+	}
+}
+
+// ParseRecords parses TXT strings into a Table.  Strings that aren't key/value pairs are ignored.
+func ParseRecords(records []string) *Table {
+	return parseRecordsFrom(records, "", SyntaxRFC1464)
+}
+
+// ParseAttributes parses DNS-SD TXT strings into a Table, using SplitAttribute.  Boolean attributes are keys with no
+// values, and only the first occurrence of each key is used.  Invalid strings are ignored.
+func ParseAttributes(records []string) *Table {
+	return parseRecordsFrom(records, "", SyntaxRFC6763)
+}
+
+func parseRecordsFrom(records []string, source string, syntax string) *Table {
+	table := makeTable()
+	for _, record := range records {
+		if syntax == SyntaxRFC6763 {
+			isAttribute, key, value, hasValue := SplitAttribute(record)
+			if !isAttribute || table.Has(key) {
+				continue
+			}
+			table.addKey(key)
+			if hasValue {
+				table.add(key, value, source)
+			}
+			continue
+		}
+		isRecord, key, value := SplitRecord(record)
+		if isRecord {
+			table.add(key, value, source)
+		}
+	}
+	return table
+}
+
+func (t *Table) addKey(key string) {
+	if _, ok := t.values[key]; !ok {
+		t.keys = append(t.keys, key)
+		t.values[key] = []string{}
+	}
+}
+
+func (t *Table) add(key string, value string, source string) {
+	t.addKey(key)
+	t.values[key] = append(t.values[key], value)
+	t.sources[key] = append(t.sources[key], source)
+}
+
+// MergeTables layers tables on top of each other, in order of precedence, using one of the Layer* policies.
+func MergeTables(policy string, tables ...*Table) (*Table, error) {
+	result := makeTable()
+	switch policy {
+	case LayerFirstWins:
+		for _, table := range tables {
+			for _, key := range table.keys {
+				if result.Has(key) {
+					continue
+				}
+				result.addKey(key)
+				for i, value := range table.values[key] {
+					result.add(key, value, table.sources[key][i])
+				}
+			}
+		}
+
+	case LayerMerge, LayerRequireAll:
+		for _, table := range tables {
+			for _, key := range table.keys {
+				if policy == LayerRequireAll && !inAllTables(key, tables) {
+					continue
+				}
+				result.addKey(key)
+				for i, value := range table.values[key] {
+					result.add(key, value, table.sources[key][i])
+				}
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown layering policy \"%s\"", policy)
+	}
+	return result, nil
+}
+
+func inAllTables(key string, tables []*Table) bool {
+	for _, table := range tables {
+		if _, ok := table.values[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// SplitRecord splits a TXT string into a key and a value.  isRecord is false if the string isn't a key/value pair.
+//
+// This implements the rules in https://tools.ietf.org/html/rfc1464#page-2
+//   - Escaping done with `
+//   - Unescaped trailing and leading tabs and spaces removed from the key
+//   - Key ends with unescaped =
+//
+// Keys are returned in lower case.
+func SplitRecord(record string) (isRecord bool, key string, value string) {
+	record = strings.TrimLeft(record, " \t")
+	var bkey []byte
+	escapeNext := false
+	numTrailingWhitespace := 0
+	for i, c := range []byte(record) {
+		if escapeNext {
+			numTrailingWhitespace = 0
+			bkey = append(bkey, c)
+			escapeNext = false
+			continue
+		}
+
+		if c == '`' {
+			escapeNext = true
+			continue
+		}
+
+		if c == '=' {
+			bkey = bkey[0 : len(bkey)-numTrailingWhitespace]
+			key = strings.ToLower(string(bkey))
+			return true, key, record[i+1 : len(record)]
+		}
+
+		if c == ' ' || c == '\t' {
+			numTrailingWhitespace++
+		} else {
+			numTrailingWhitespace = 0
+		}
+		bkey = append(bkey, c)
+	}
+	return false, "", ""
+}
+
+// SplitAttribute splits a DNS-SD TXT string into a key and an optional value.  isAttribute is false if the string
+// isn't a valid attribute.
+//
+// This implements the rules in https://tools.ietf.org/html/rfc6763#section-6.3
+//   - Key ends with the first =, and there's no escaping
+//   - A string without = is a boolean attribute, with hasValue false
+//   - Keys must be at least one printable US-ASCII character, and spaces are significant
+//   - Values are opaque bytes
+//
+// Keys are returned in lower case.
+func SplitAttribute(record string) (isAttribute bool, key string, value string, hasValue bool) {
+	key = record
+	if i := strings.IndexByte(record, '='); i >= 0 {
+		key, value, hasValue = record[:i], record[i+1:], true
+	}
+	if key == "" {
+		return false, "", "", false
+	}
+	for _, c := range []byte(key) {
+		if c < 0x20 || c > 0x7e {
+			return false, "", "", false
+		}
+	}
+	return true, strings.ToLower(key), value, hasValue
+}
+
+// Lookup returns the value of a key that's expected to have exactly one value.
+// If the key isn't found, or has more than one value, the error is a *KeyError.
+func (t *Table) Lookup(key string) (string, error) {
+	values := t.LookupList(key)
+	if len(values) == 0 {
+		return "", &KeyError{Key: strings.ToLower(key), Err: ErrKeyNotFound}
+	}
+	if len(values) > 1 {
+		return "", &KeyError{Key: strings.ToLower(key), Count: len(values), Err: ErrTooManyValues}
+	}
+	return values[0], nil
+}
+
+// Has returns whether a key is in the table, even if it has no values (like a DNS-SD boolean attribute).
+func (t *Table) Has(key string) bool {
+	_, ok := t.values[strings.ToLower(key)]
+	return ok
+}
+
+// LookupList returns all values of a key, in record order.
+func (t *Table) LookupList(key string) []string {
+	return t.values[strings.ToLower(key)]
+}
+
+// LookupSources returns the sources of all values of a key, in the same order as LookupList.
+func (t *Table) LookupSources(key string) []string {
+	return t.sources[strings.ToLower(key)]
+}
+
+// Keys returns all keys in the table, sorted.
+func (t *Table) Keys() []string {
+	keys := append([]string(nil), t.keys...)
+	sort.Strings(keys)
+	return keys
+}
+
+// Map returns all keys in the table, mapped to their values.
+func (t *Table) Map() map[string][]string {
+	result := make(map[string][]string, len(t.values))
+	for key, values := range t.values {
+		result[key] = append([]string{}, values...)
+	}
+	return result
+}
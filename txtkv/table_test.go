@@ -0,0 +1,176 @@
+package txtkv
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var sampleTxtRecords = []string{
+	"foo=bar",
+	"multival=1",
+	"multival=2",
+	"something that's not a key/value pair",
+	"CamelCase=CamelCaseValue",
+	"` key`=with escapes`\t \t=`escaped`value",
+}
+
+type splitRecordTestPair struct {
+	Input    string
+	IsRecord bool
+	Key      string
+	Value    string
+}
+
+func TestSplitRecord(t *testing.T) {
+	for _, testPair := range []splitRecordTestPair{
+		{"", false, "", ""},
+		{"notkv", false, "", ""},
+		{"=", true, "", ""},
+		{"`=", false, "", ""},
+		{"foo=bar", true, "foo", "bar"},
+		{"Foo=Bar", true, "foo", "Bar"},
+		{"a=b=c", true, "a", "b=c"},
+		{"\t with tabs\tand spaces \t=  whitespace value\t ", true, "with tabs\tand spaces", "  whitespace value\t "},
+		{"` key`=with escapes`\t \t=`escaped`value", true, " key=with escapes\t", "`escaped`value"},
+	} {
+		isRecord, key, value := SplitRecord(testPair.Input)
+		if isRecord != testPair.IsRecord || key != testPair.Key || value != testPair.Value {
+			t.Error("Expected", testPair.IsRecord, testPair.Key, testPair.Value, "but got", isRecord, key, value, "for", testPair)
+		}
+	}
+}
+
+type splitAttributeTestPair struct {
+	Input       string
+	IsAttribute bool
+	Key         string
+	Value       string
+	HasValue    bool
+}
+
+func TestSplitAttribute(t *testing.T) {
+	for _, testPair := range []splitAttributeTestPair{
+		{"", false, "", "", false},
+		{"=", false, "", "", false},
+		{"=value", false, "", "", false},
+		{"PaperTray", true, "papertray", "", false},
+		{"key=", true, "key", "", true},
+		{"Foo=Bar", true, "foo", "Bar", true},
+		{"a=b=c", true, "a", "b=c", true},
+		{" spaced key =  value ", true, " spaced key ", "  value ", true},
+		{"`key`=value", true, "`key`", "value", true},
+		{"caf\xc3\xa9=x", false, "", "", false},
+		{"tab\tkey=x", false, "", "", false},
+		{"bin=\x00\xff", true, "bin", "\x00\xff", true},
+	} {
+		isAttribute, key, value, hasValue := SplitAttribute(testPair.Input)
+		if isAttribute != testPair.IsAttribute || key != testPair.Key || value != testPair.Value || hasValue != testPair.HasValue {
+			t.Error("Expected", testPair.IsAttribute, testPair.Key, testPair.Value, testPair.HasValue, "but got", isAttribute, key, value, hasValue, "for", testPair)
+		}
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	table := ParseAttributes([]string{"txtvers=1", "PaperTray", "empty=", "TXTVERS=2", "papertray=yes", "=junk"})
+
+	if values, expected := table.LookupList("txtvers"), []string{"1"}; !reflect.DeepEqual(values, expected) {
+		t.Error("Expected", expected, "but got", values)
+	}
+	if !table.Has("PaperTray") {
+		t.Error("Expected boolean attribute papertray to be present")
+	}
+	if values := table.LookupList("papertray"); len(values) != 0 {
+		t.Error("Expected no values for boolean attribute but got", values)
+	}
+	if value, err := table.Lookup("empty"); err != nil || value != "" {
+		t.Error("Expected empty value but got", value, err)
+	}
+	if table.Has("nosuchkey") {
+		t.Error("Expected nosuchkey to be absent")
+	}
+	if keys, expected := table.Keys(), []string{"empty", "papertray", "txtvers"}; !reflect.DeepEqual(keys, expected) {
+		t.Error("Expected", expected, "but got", keys)
+	}
+
+	merged, err := MergeTables(LayerFirstWins, table, ParseRecords([]string{"papertray=no", "other=1"}))
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if !merged.Has("papertray") || len(merged.LookupList("papertray")) != 0 || !merged.Has("other") {
+		t.Error("Expected boolean attribute to win over later value but got", merged.Map())
+	}
+}
+
+func TestTableLookup(t *testing.T) {
+	table := ParseRecords(sampleTxtRecords)
+
+	value, err := table.Lookup("FOO")
+	if err != nil {
+		t.Error("Error", err.Error())
+	}
+	if value != "bar" {
+		t.Error("Expected bar but got", value)
+	}
+
+	_, err = table.Lookup("nosuchkey")
+	var keyError *KeyError
+	if !errors.Is(err, ErrKeyNotFound) || !errors.As(err, &keyError) || keyError.Key != "nosuchkey" {
+		t.Error("Expected ErrKeyNotFound KeyError but got", err)
+	}
+
+	_, err = table.Lookup("multival")
+	if !errors.Is(err, ErrTooManyValues) || !errors.As(err, &keyError) || keyError.Count != 2 {
+		t.Error("Expected ErrTooManyValues KeyError but got", err)
+	}
+
+	if values, expected := table.LookupList("multival"), []string{"1", "2"}; !reflect.DeepEqual(values, expected) {
+		t.Error("Expected", expected, "but got", values)
+	}
+	if values := table.LookupList("nosuchkey"); len(values) != 0 {
+		t.Error("Expected no values but got", values)
+	}
+
+	expectedKeys := []string{" key=with escapes\t", "camelcase", "foo", "multival"}
+	if keys := table.Keys(); !reflect.DeepEqual(keys, expectedKeys) {
+		t.Error("Expected", expectedKeys, "but got", keys)
+	}
+}
+
+type mergeTablesTestPair struct {
+	Policy  string
+	Key     string
+	Values  []string
+	Sources []string
+}
+
+func TestMergeTables(t *testing.T) {
+	override := parseRecordsFrom([]string{"a=override", "list=1"}, "override", SyntaxRFC1464)
+	defaults := parseRecordsFrom([]string{"a=default", "b=default", "list=2", "list=3"}, "defaults", SyntaxRFC1464)
+	for _, testPair := range []mergeTablesTestPair{
+		{LayerFirstWins, "a", []string{"override"}, []string{"override"}},
+		{LayerFirstWins, "b", []string{"default"}, []string{"defaults"}},
+		{LayerFirstWins, "list", []string{"1"}, []string{"override"}},
+		{LayerMerge, "a", []string{"override", "default"}, []string{"override", "defaults"}},
+		{LayerMerge, "b", []string{"default"}, []string{"defaults"}},
+		{LayerMerge, "list", []string{"1", "2", "3"}, []string{"override", "defaults", "defaults"}},
+		{LayerRequireAll, "a", []string{"override", "default"}, []string{"override", "defaults"}},
+		{LayerRequireAll, "b", nil, nil},
+	} {
+		table, err := MergeTables(testPair.Policy, override, defaults)
+		if err != nil {
+			t.Error("Error", err.Error(), "for", testPair)
+			continue
+		}
+		if values := table.LookupList(testPair.Key); !reflect.DeepEqual(values, testPair.Values) {
+			t.Error("Expected", testPair.Values, "but got", values, "for", testPair)
+		}
+		if sources := table.LookupSources(testPair.Key); !reflect.DeepEqual(sources, testPair.Sources) {
+			t.Error("Expected", testPair.Sources, "but got", sources, "for", testPair)
+		}
+	}
+
+	if _, err := MergeTables("last-wins", override, defaults); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
@@ -0,0 +1,127 @@
+package txtkv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Trace output goes through the context, so that it reaches everything that makes queries without every function
+// needing the options
+type traceKey struct{}
+
+// Lines start with ";;", like comments in dig output
+const tracePrefix = ";; "
+
+func (c *Client) traceContext(ctx context.Context) context.Context {
+	if c.options.Trace == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, traceKey{}, c.options.Trace)
+}
+
+func tracef(ctx context.Context, format string, args ...interface{}) {
+	trace, _ := ctx.Value(traceKey{}).(io.Writer)
+	if trace == nil {
+		return
+	}
+	fmt.Fprintf(trace, tracePrefix+format+"\n", args...)
+}
+
+func tracing(ctx context.Context) bool {
+	return ctx.Value(traceKey{}) != nil
+}
+
+func traceSource(ctx context.Context, source string) {
+	if !tracing(ctx) {
+		return
+	}
+	if !strings.ContainsRune(source, ':') {
+		tracef(ctx, "source %s: DNS domain name", source)
+		return
+	}
+	uri, err := parseURI(source)
+	if err != nil {
+		return
+	}
+	tracef(ctx, "source %s: scheme %q, authority %q, path %q, query %q", source, uri.Scheme, uri.Authority, uri.Path, uri.RawQuery)
+}
+
+func traceQuery(ctx context.Context, query *dns.Msg, server string, transport string) {
+	if !tracing(ctx) {
+		return
+	}
+	question := query.Question[0]
+	tracef(ctx, "query %s %s %s to %s over %s, flags: %s", question.Name, dns.ClassToString[question.Qclass], dns.TypeToString[question.Qtype], server, transport, messageFlags(query))
+}
+
+func traceResponse(ctx context.Context, response *dns.Msg, server string) {
+	if !tracing(ctx) {
+		return
+	}
+	tracef(ctx, "response from %s: %s, flags: %s, %d answers", server, dns.RcodeToString[response.Rcode], messageFlags(response), len(response.Answer))
+	for _, rr := range response.Answer {
+		tracef(ctx, "  answer: %s", rr.String())
+	}
+}
+
+func messageFlags(message *dns.Msg) string {
+	var flags []string
+	for _, flag := range []struct {
+		set  bool
+		name string
+	}{
+		{message.Response, "qr"},
+		{message.Authoritative, "aa"},
+		{message.Truncated, "tc"},
+		{message.RecursionDesired, "rd"},
+		{message.RecursionAvailable, "ra"},
+		{message.AuthenticatedData, "ad"},
+		{message.CheckingDisabled, "cd"},
+	} {
+		if flag.set {
+			flags = append(flags, flag.name)
+		}
+	}
+	if opt := message.IsEdns0(); opt != nil && opt.Do() {
+		flags = append(flags, "do")
+	}
+	if len(flags) == 0 {
+		return "none"
+	}
+	return strings.Join(flags, " ")
+}
+
+// Explains what parseRecordsFrom does with each (unquoted) TXT string
+func traceRecords(ctx context.Context, records []string, syntax string) {
+	if !tracing(ctx) {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if syntax == SyntaxRFC6763 {
+			isAttribute, key, value, hasValue := SplitAttribute(record)
+			switch {
+			case !isAttribute:
+				tracef(ctx, "rejected %q: the key is empty or has characters that aren't printable ASCII", record)
+			case seen[key]:
+				tracef(ctx, "ignored %q: only the first %q attribute counts", record, key)
+			case hasValue:
+				tracef(ctx, "accepted %q: key %q, value %q", record, key, value)
+			default:
+				tracef(ctx, "accepted %q: boolean attribute %q", record, key)
+			}
+			seen[key] = true
+			continue
+		}
+		isRecord, key, value := SplitRecord(record)
+		if isRecord {
+			tracef(ctx, "accepted %q: key %q, value %q", record, key, value)
+		} else {
+			tracef(ctx, "rejected %q: no unescaped \"=\"", record)
+		}
+	}
+}
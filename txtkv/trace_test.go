@@ -0,0 +1,58 @@
+package txtkv
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	nameserver := startTestServer(t, "127.0.0.1:0", []string{`foo.example.com. 300 IN TXT "a=1" "b\"" "not a record"`})
+
+	var trace strings.Builder
+	client := NewClient(Options{Nameserver: nameserver, Trace: &trace})
+	if _, err := client.Table(context.Background(), "foo.example.com"); err != nil {
+		t.Fatal("Error", err.Error())
+	}
+
+	for _, expected := range []string{
+		";; source foo.example.com: DNS domain name\n",
+		";; using nameserver " + nameserver + " (default nameserver)\n",
+		";; query foo.example.com. IN TXT to " + nameserver + " over TCP, flags: rd\n",
+		";; response from " + nameserver + ": NOERROR, flags: qr aa rd, 1 answers\n",
+		";;   answer: foo.example.com.\t300\tIN\tTXT\t\"a=1\" \"b\\\"\" \"not a record\"\n",
+		";; 1 TXT strings from foo.example.com\n",
+		";; accepted \"a=1b\\\"not a record\": key \"a\", value \"1b\\\"not a record\"\n",
+	} {
+		if !strings.Contains(trace.String(), expected) {
+			t.Error("Expected", expected, "in trace", trace.String())
+		}
+	}
+
+	source := writeTestRecords(t, "\"a=1\"\n\"not a record\"\n\"a=2\"\n\"\"\n")
+	for _, testPair := range []struct {
+		Syntax   string
+		Expected []string
+	}{
+		{SyntaxRFC1464, []string{
+			";; accepted \"a=1\": key \"a\", value \"1\"\n",
+			";; rejected \"not a record\": no unescaped \"=\"\n",
+		}},
+		{SyntaxRFC6763, []string{
+			";; accepted \"not a record\": boolean attribute \"not a record\"\n",
+			";; ignored \"a=2\": only the first \"a\" attribute counts\n",
+			";; rejected \"\": the key is empty or has characters that aren't printable ASCII\n",
+		}},
+	} {
+		trace.Reset()
+		client := NewClient(Options{Syntax: testPair.Syntax, Trace: &trace})
+		if _, err := client.Table(context.Background(), source); err != nil {
+			t.Fatal("Error", err.Error())
+		}
+		for _, expected := range testPair.Expected {
+			if !strings.Contains(trace.String(), expected) {
+				t.Error("Expected", expected, "in trace", trace.String(), "for", testPair.Syntax)
+			}
+		}
+	}
+}
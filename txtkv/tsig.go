@@ -0,0 +1,78 @@
+package txtkv
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// TSIGKey is a shared secret for signing DNS queries and responses with TSIG (RFC 8945), like the keys in a BIND
+// named.conf.
+type TSIGKey struct {
+	// Name is the key name, which must match the nameserver's configuration.
+	Name string
+	// Algorithm is one of hmac-md5, hmac-sha1, hmac-sha256 or hmac-sha512.  If empty, it's hmac-sha256.
+	Algorithm string
+	// Secret is the base64-encoded secret.
+	Secret string
+}
+
+var tsigAlgorithms = map[string]string{
+	"hmac-md5":    dns.HmacMD5,
+	"hmac-sha1":   dns.HmacSHA1,
+	"hmac-sha256": dns.HmacSHA256,
+	"hmac-sha512": dns.HmacSHA512,
+}
+
+// How far the clocks of the client and nameserver can disagree, as recommended by RFC 8945
+const tsigFudge = 300
+
+// ParseTSIGKey reads a key in the same "[algorithm:]name:secret" form as dig -y.
+func ParseTSIGKey(spec string) (*TSIGKey, error) {
+	parts := strings.Split(spec, ":")
+	key := &TSIGKey{}
+	switch len(parts) {
+	case 2:
+		key.Name, key.Secret = parts[0], parts[1]
+	case 3:
+		key.Algorithm, key.Name, key.Secret = strings.ToLower(parts[0]), parts[1], parts[2]
+	default:
+		return nil, errors.New("TSIG key must look like [algorithm:]name:secret")
+	}
+	if key.Name == "" {
+		return nil, errors.New("TSIG key name is empty")
+	}
+	if _, err := key.algorithm(); err != nil {
+		return nil, err
+	}
+	if _, err := base64.StdEncoding.DecodeString(key.Secret); err != nil || key.Secret == "" {
+		return nil, fmt.Errorf("TSIG key %s has an invalid secret: must be base64", key.Name)
+	}
+	return key, nil
+}
+
+func (k *TSIGKey) algorithm() (string, error) {
+	if k.Algorithm == "" {
+		return dns.HmacSHA256, nil
+	}
+	algorithm, ok := tsigAlgorithms[k.Algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported TSIG algorithm %s: must be hmac-md5, hmac-sha1, hmac-sha256 or hmac-sha512", k.Algorithm)
+	}
+	return algorithm, nil
+}
+
+// sign adds a TSIG record to the query, and returns the secrets the client needs to sign it and check the response
+func (k *TSIGKey) sign(query *dns.Msg) (map[string]string, error) {
+	algorithm, err := k.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	name := strings.ToLower(dns.Fqdn(k.Name))
+	query.SetTsig(name, algorithm, tsigFudge, time.Now().Unix())
+	return map[string]string{name: k.Secret}, nil
+}
@@ -0,0 +1,123 @@
+package txtkv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type parseTSIGKeyTestPair struct {
+	Spec     string
+	Expected *TSIGKey
+}
+
+func TestParseTSIGKey(t *testing.T) {
+	for _, testPair := range []parseTSIGKeyTestPair{
+		{"prod-key:c2VjcmV0", &TSIGKey{Name: "prod-key", Secret: "c2VjcmV0"}},
+		{"hmac-sha512:prod-key:c2VjcmV0", &TSIGKey{Name: "prod-key", Algorithm: "hmac-sha512", Secret: "c2VjcmV0"}},
+		{"HMAC-SHA1:prod-key:c2VjcmV0", &TSIGKey{Name: "prod-key", Algorithm: "hmac-sha1", Secret: "c2VjcmV0"}},
+		{"prod-key", nil},
+		{":c2VjcmV0", nil},
+		{"prod-key:", nil},
+		{"prod-key:not base64!", nil},
+		{"hmac-sha3:prod-key:c2VjcmV0", nil},
+		{"hmac-sha256:prod-key:c2VjcmV0:extra", nil},
+	} {
+		key, err := ParseTSIGKey(testPair.Spec)
+		if testPair.Expected == nil {
+			if err == nil {
+				t.Error("Expected an error for", testPair.Spec, "but got", key)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error("Error", err.Error(), "for", testPair.Spec)
+			continue
+		}
+		if !reflect.DeepEqual(key, testPair.Expected) {
+			t.Error("Expected", testPair.Expected, "but got", key, "for", testPair.Spec)
+		}
+	}
+}
+
+// Starts an in-process DNS server that only answers queries signed with the key, like a nameserver with a BIND
+// allow-query { key ...; } rule
+func startTSIGTestServer(t *testing.T, key *TSIGKey, records []string) string {
+	var rrs []dns.RR
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatal("Error", err.Error(), "parsing test record", record)
+		}
+		rrs = append(rrs, rr)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	server := &dns.Server{
+		Listener:   listener,
+		TsigSecret: map[string]string{dns.Fqdn(key.Name): key.Secret},
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, query *dns.Msg) {
+			tsig := query.IsTsig()
+			if tsig == nil || w.TsigStatus() != nil {
+				response := new(dns.Msg)
+				response.SetRcode(query, dns.RcodeNotAuth)
+				w.WriteMsg(response)
+				return
+			}
+			response := testServerResponse(rrs, query)
+			response.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsigFudge, time.Now().Unix())
+			w.WriteMsg(response)
+		}),
+	}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return listener.Addr().String()
+}
+
+type tsigTestPair struct {
+	Key *TSIGKey
+	Err error
+}
+
+func TestClientTSIG(t *testing.T) {
+	key := &TSIGKey{Name: "prod-key", Secret: "c2VjcmV0"}
+	nameserver := startTSIGTestServer(t, key, []string{`foo.example.com. 300 IN TXT "a=1"`})
+	unsigned := startTestServer(t, "127.0.0.1:0", []string{`foo.example.com. 300 IN TXT "a=1"`})
+
+	for _, testPair := range []tsigTestPair{
+		{key, nil},
+		{&TSIGKey{Name: "prod-key", Algorithm: "hmac-sha256", Secret: "c2VjcmV0"}, nil},
+		{&TSIGKey{Name: "prod-key", Secret: "b3RoZXI="}, ErrBadTSIG},
+		{&TSIGKey{Name: "other-key", Secret: "c2VjcmV0"}, ErrBadTSIG},
+		{nil, ErrServerFailure},
+	} {
+		client := NewClient(Options{Nameserver: nameserver, TSIGKey: testPair.Key})
+		table, err := client.Table(context.Background(), "foo.example.com")
+		if !errors.Is(err, testPair.Err) {
+			t.Error("Expected", testPair.Err, "but got", err, "for", testPair.Key)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(table.LookupList("a"), []string{"1"}) {
+			t.Error("Expected a=1 but got", table, "for", testPair.Key)
+		}
+	}
+
+	client := NewClient(Options{Nameserver: unsigned, TSIGKey: key})
+	if _, err := client.Table(context.Background(), "foo.example.com"); !errors.Is(err, ErrBadTSIG) {
+		t.Error("Expected ErrBadTSIG for an unsigned response but got", err)
+	}
+
+	client = NewClient(Options{Resolve: ResolveIterative, TSIGKey: key})
+	var sourceErr *SourceError
+	if _, err := client.Table(context.Background(), "foo.example.com"); !errors.As(err, &sourceErr) {
+		t.Error("Expected a SourceError for iterative resolution with a TSIG key but got", err)
+	}
+}
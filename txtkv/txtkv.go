@@ -0,0 +1,334 @@
+// Package txtkv uses sets of DNS TXT records as key/value tables.
+//
+// Records follow RFC 1464: each TXT string is a key and a value separated by the first unescaped "=".  Keys are
+// case-insensitive, and repeated keys are interpreted as lists.  See SplitRecord for the full rules.
+//
+// Records can come from DNS or from a file, depending on the source given to a Client:
+//
+//	foo.example.com
+//	dns:foo.example.com
+//	dns://127.0.0.1:53/foo.example.com?timeout=2s&dnssec=1
+//	file:///tmp/records
+//	mdns:printer.local?window=2s
+//	snapshot:///var/lib/sdget/foo.snap?max-age=24h
+//
+// Other URI schemes can be added with RegisterScheme.
+package txtkv
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resolution methods for Options.Resolve
+const (
+	ResolveRecursive = "recursive"
+	ResolveIterative = "iterative"
+)
+
+// Options configures a Client.  The zero value is ready to use.
+type Options struct {
+	// Nameserver is the default nameserver address (e.g. "ns.example.com:53" or "127.0.0.1") for DNS sources that
+	// don't specify one.  If empty, the first nameserver in /etc/resolv.conf is used.
+	Nameserver string
+
+	// BaseDomain, if set, makes the domain names of DNS sources relative to it: "app" (or "dns:app") means
+	// "app.<BaseDomain>".  Names with a trailing dot ("app.example.com.") are absolute.  The names given to
+	// CheckConsistency, Browse and ResolveInstance are relative to it too.
+	BaseDomain string
+
+	// Resolve is the DNS resolution method, either ResolveRecursive (the default) or ResolveIterative.  Iterative
+	// resolution starts at the root servers and follows referrals instead of using Nameserver.
+	Resolve string
+
+	// DNSSEC requires DNS responses to be authenticated by a DNSSEC-validating resolver, for DNS sources that don't
+	// set the dnssec parameter themselves.  It can't be used with iterative resolution.
+	DNSSEC bool
+
+	// TSIGKey, if set, signs the queries to the nameserver of DNS sources, and requires the responses to be signed with
+	// the same key.  It can't be used with iterative resolution.
+	TSIGKey *TSIGKey
+
+	// RootHints is the path to a root hints file (in named.root format) for iterative resolution.  If empty,
+	// built-in root server addresses are used.
+	RootHints string
+
+	// Includes enables include directives: records with the reserved key IncludeKey pull in the records of other
+	// sources.  See Client.Table for the rules.
+	Includes bool
+
+	// MaxIncludeDepth limits how deeply include directives can be nested.  If zero, the limit is 8.
+	MaxIncludeDepth int
+
+	// Syntax is the TXT record syntax, either SyntaxRFC1464 or SyntaxRFC6763.  If empty, sources use SyntaxRFC1464,
+	// and DNS-SD service instances use SyntaxRFC6763.  With SyntaxRFC6763, each string of a DNS TXT record is a
+	// separate attribute.
+	Syntax string
+
+	// MulticastWindow is how long to collect mDNS responses for, for mdns sources and DNS-SD names under ".local".  If
+	// zero, it's 1s.
+	MulticastWindow time.Duration
+
+	// MaxSnapshotAge is how old the records of snapshot sources can be before lookups fail with ErrStaleSnapshot, for
+	// snapshot sources that don't set the max-age parameter themselves.  If zero, snapshots never expire.
+	MaxSnapshotAge time.Duration
+
+	// VerifyKeys are the trusted keys for signed sources.  If any are set, every source (including included sources and
+	// DNS-SD service instances) must have a valid SignatureKey record from one of them, or lookups fail.
+	VerifyKeys []ed25519.PublicKey
+
+	// DecryptKeys are the X25519 private keys for values encrypted with EncryptValue.  Interpolate uses them to
+	// decrypt referenced values.
+	DecryptKeys []*ecdh.PrivateKey
+
+	// Trace, if set, gets a log of each step of a lookup, for debugging: how sources are parsed, which nameservers are
+	// used, the DNS queries and responses, and what happens to each TXT string.
+	Trace io.Writer
+
+	// ReuseFetches keeps the records of every source the Client fetches, so that later lookups of the same source
+	// don't fetch it again (errors included).  It's meant for short-lived Clients, like for a batch of lookups.
+	ReuseFetches bool
+
+	// MaxConcurrentFetches limits how many sources are fetched at the same time, when several are needed at once
+	// (layered sources, includes, or concurrent calls to the same Client).  If zero, the limit is 8.
+	MaxConcurrentFetches int
+}
+
+// Provider is a source of TXT records.
+type Provider interface {
+	// TxtRecords returns the (unquoted) TXT strings of the source, in no particular order.
+	TxtRecords(ctx context.Context) ([]string, error)
+}
+
+// Client looks up keys in TXT record sources.  It's safe to use from multiple goroutines, and concurrent fetches of
+// the same source share one query.
+type Client struct {
+	options Options
+	fetches *fetchGroup
+	slots   chan struct{}
+}
+
+// NewClient returns a Client using the given options.
+func NewClient(options Options) *Client {
+	if options.Trace != nil {
+		options.Trace = &syncWriter{writer: options.Trace}
+	}
+	maxFetches := options.MaxConcurrentFetches
+	if maxFetches <= 0 {
+		maxFetches = defaultMaxConcurrentFetches
+	}
+	return &Client{
+		options: options,
+		fetches: &fetchGroup{calls: make(map[string]*fetchCall), keep: options.ReuseFetches},
+		slots:   make(chan struct{}, maxFetches),
+	}
+}
+
+// Provider returns the Provider for a source, which is either a URI or a plain domain name.
+// Errors are of type *SourceError.
+func (c *Client) Provider(source string) (Provider, error) {
+	provider, err := c.provider(source)
+	if err != nil {
+		return nil, &SourceError{Source: source, Err: err}
+	}
+	return provider, nil
+}
+
+func (c *Client) provider(source string) (Provider, error) {
+	options := &c.options
+	if strings.ContainsRune(source, ':') {
+		uri, err := parseURI(source)
+		if err != nil {
+			return nil, err
+		}
+		scheme, ok := lookUpScheme(uri.Scheme)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedScheme, uri.Scheme)
+		}
+		if err := scheme.validate(uri); err != nil {
+			return nil, err
+		}
+		return scheme.New(options, uri)
+	}
+	return makeDnsProvider(options, "", source)
+}
+
+// Table fetches the TXT records of a source and parses them into a Table.
+//
+// If Options.Includes is set, the sources named by IncludeKey records are fetched and merged into the table as well.
+// Keys in the including source take precedence over keys from included sources.  If more than one included source
+// has a key, all their values are kept (so a lookup expecting a single value fails rather than picking one
+// arbitrarily).  Included sources can have their own includes, but cycles and nesting deeper than
+// Options.MaxIncludeDepth are errors.  Network sources (see Scheme.Network) can only include other network sources,
+// and local includes from them are errors matching ErrLocalReference.
+func (c *Client) Table(ctx context.Context, source string) (*Table, error) {
+	table, err := c.fetchTable(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if c.options.Includes {
+		return c.expandIncludes(ctx, table, []string{source}, map[string]bool{canonicalSource(source): true})
+	}
+	return table, nil
+}
+
+func (c *Client) fetchTable(ctx context.Context, source string) (*Table, error) {
+	ctx = c.traceContext(ctx)
+	traceSource(ctx, source)
+	provider, err := c.Provider(source)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetches.do(ctx, fetchKey(source, provider), source, func() (*Table, error) {
+		return c.fetchTableNow(ctx, source, provider)
+	})
+}
+
+func (c *Client) fetchTableNow(ctx context.Context, source string, provider Provider) (*Table, error) {
+	if err := c.acquire(ctx); err != nil {
+		return nil, &FetchError{Source: source, Err: err}
+	}
+	records, err := provider.TxtRecords(ctx)
+	c.release()
+	if err != nil {
+		tracef(ctx, "error fetching %s: %s", source, err.Error())
+		return nil, &FetchError{Source: source, Err: err}
+	}
+	tracef(ctx, "%d TXT strings from %s", len(records), source)
+	traceRecords(ctx, records, c.options.Syntax)
+	table := parseRecordsFrom(records, source, c.options.Syntax)
+	if err := c.verify(table, source, RecordName(provider)); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// verify checks the signatures of a table from a source, with the records published under a DNS name
+func (c *Client) verify(table *Table, source string, name string) error {
+	if len(c.options.VerifyKeys) == 0 {
+		return nil
+	}
+	if name == "" {
+		return fmt.Errorf("error verifying %s: %w: the source has no DNS name to check signatures against (file sources need a name parameter)", source, ErrBadSignature)
+	}
+	if err := VerifyTable(table, c.options.VerifyKeys, name, time.Now()); err != nil {
+		return fmt.Errorf("error verifying %s: %w", source, err)
+	}
+	return nil
+}
+
+// LayeredTable fetches the Tables of several sources, in order of precedence, and merges them with MergeTables.
+//
+// For the LayerFirstWins and LayerMerge policies, sources with no TXT records at all (like domains that don't exist)
+// are skipped, unless none of the sources have records.  LayerRequireAll needs every source to exist.
+//
+// The sources are fetched concurrently, but errors are reported as if they were fetched in order.
+func (c *Client) LayeredTable(ctx context.Context, sources []string, policy string) (*Table, error) {
+	var tables []*Table
+	var lastErr error
+	for _, result := range fetchAll(ctx, sources, c.Table) {
+		table, err := result.table, result.err
+		if err != nil {
+			if policy != LayerRequireAll && errors.Is(err, ErrNoRecords) {
+				lastErr = err
+				continue
+			}
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	if len(tables) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return MergeTables(policy, tables...)
+}
+
+// Lookup returns the value of a key that's expected to have exactly one value in the source.
+// If the key isn't found, or has more than one value, the error is a *KeyError matching ErrKeyNotFound or
+// ErrTooManyValues.
+func (c *Client) Lookup(ctx context.Context, source string, key string) (string, error) {
+	table, err := c.Table(ctx, source)
+	if err != nil {
+		return "", err
+	}
+	return table.Lookup(key)
+}
+
+// LookupList returns all values of a key in the source.  A key that isn't found has no values, which isn't an error.
+func (c *Client) LookupList(ctx context.Context, source string, key string) ([]string, error) {
+	table, err := c.Table(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	return table.LookupList(key), nil
+}
+
+// All returns every key in the source, mapped to its values.
+func (c *Client) All(ctx context.Context, source string) (map[string][]string, error) {
+	table, err := c.Table(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	return table.Map(), nil
+}
+
+// LookupInt is like Lookup, but parses the value as a decimal integer.  Parsing errors are of type *ValueError.
+func (c *Client) LookupInt(ctx context.Context, source string, key string) (int, error) {
+	value, err := c.Lookup(ctx, source, key)
+	if err != nil {
+		return 0, err
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &ValueError{Key: key, Value: value, Type: "int", Err: err}
+	}
+	return result, nil
+}
+
+// LookupFloat is like Lookup, but parses the value as a floating point number.  Parsing errors are of type *ValueError.
+func (c *Client) LookupFloat(ctx context.Context, source string, key string) (float64, error) {
+	value, err := c.Lookup(ctx, source, key)
+	if err != nil {
+		return 0, err
+	}
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, &ValueError{Key: key, Value: value, Type: "float", Err: err}
+	}
+	return result, nil
+}
+
+// LookupBool is like Lookup, but parses the value as a boolean using strconv.ParseBool.  Parsing errors are of type
+// *ValueError.
+func (c *Client) LookupBool(ctx context.Context, source string, key string) (bool, error) {
+	value, err := c.Lookup(ctx, source, key)
+	if err != nil {
+		return false, err
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, &ValueError{Key: key, Value: value, Type: "bool", Err: err}
+	}
+	return result, nil
+}
+
+// LookupDuration is like Lookup, but parses the value using time.ParseDuration.  Parsing errors are of type
+// *ValueError.
+func (c *Client) LookupDuration(ctx context.Context, source string, key string) (time.Duration, error) {
+	value, err := c.Lookup(ctx, source, key)
+	if err != nil {
+		return 0, err
+	}
+	result, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, &ValueError{Key: key, Value: value, Type: "duration", Err: err}
+	}
+	return result, nil
+}
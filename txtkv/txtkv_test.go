@@ -0,0 +1,169 @@
+package txtkv
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func writeTestRecords(t *testing.T, records string) string {
+	path := filepath.Join(t.TempDir(), "records")
+	if err := ioutil.WriteFile(path, []byte(records), 0644); err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	return "file://" + path
+}
+
+func TestClientLookup(t *testing.T) {
+	source := writeTestRecords(t, `"replicas=3"
+"ratio=0.5"
+"enabled=true"
+"timeout=1m30s"
+"name=three"
+"list=a"
+"list=b"
+`)
+	client := NewClient(Options{})
+	ctx := context.Background()
+
+	if value, err := client.Lookup(ctx, source, "name"); err != nil || value != "three" {
+		t.Error("Expected three but got", value, err)
+	}
+	if value, err := client.LookupInt(ctx, source, "replicas"); err != nil || value != 3 {
+		t.Error("Expected 3 but got", value, err)
+	}
+	if value, err := client.LookupFloat(ctx, source, "ratio"); err != nil || value != 0.5 {
+		t.Error("Expected 0.5 but got", value, err)
+	}
+	if value, err := client.LookupBool(ctx, source, "enabled"); err != nil || !value {
+		t.Error("Expected true but got", value, err)
+	}
+	if value, err := client.LookupDuration(ctx, source, "timeout"); err != nil || value != 90*time.Second {
+		t.Error("Expected 1m30s but got", value, err)
+	}
+	if values, err := client.LookupList(ctx, source, "list"); err != nil || !reflect.DeepEqual(values, []string{"a", "b"}) {
+		t.Error("Expected [a b] but got", values, err)
+	}
+
+	_, err := client.LookupInt(ctx, source, "name")
+	var valueError *ValueError
+	if !errors.As(err, &valueError) || valueError.Type != "int" {
+		t.Error("Expected ValueError but got", err)
+	}
+
+	all, err := client.All(ctx, source)
+	if err != nil {
+		t.Error("Error", err.Error())
+	}
+	if len(all) != 6 {
+		t.Error("Expected 6 keys but got", all)
+	}
+}
+
+func TestClientProvider(t *testing.T) {
+	client := NewClient(Options{})
+	for _, source := range []string{"ftp://example.com/records", "dns:example.com?type=A", "nope:"} {
+		_, err := client.Provider(source)
+		var sourceError *SourceError
+		if !errors.As(err, &sourceError) {
+			t.Error("Expected SourceError but got", err, "for", source)
+		}
+	}
+
+	_, err := client.Provider("gopher://example.com/")
+	if !errors.Is(err, ErrUnsupportedScheme) {
+		t.Error("Expected ErrUnsupportedScheme but got", err)
+	}
+
+	_, err = client.Lookup(context.Background(), "file://"+os.DevNull, "foo")
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Error("Expected ErrKeyNotFound but got", err)
+	}
+}
+
+func TestClientLayeredTable(t *testing.T) {
+	server := startTestServer(t, "127.0.0.1:0", []string{
+		`defaults.example.com. 300 IN TXT "key=default"`,
+	})
+	client := NewClient(Options{Nameserver: server})
+	ctx := context.Background()
+	local := writeTestRecords(t, `"key=local"`)
+	sources := []string{"dns:missing.example.com", local, "dns:defaults.example.com"}
+
+	table, err := client.LayeredTable(ctx, sources, LayerFirstWins)
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	if values, expected := table.LookupList("key"), []string{"local"}; !reflect.DeepEqual(values, expected) {
+		t.Error("Expected", expected, "but got", values)
+	}
+	if sources, expected := table.LookupSources("key"), []string{local}; !reflect.DeepEqual(sources, expected) {
+		t.Error("Expected", expected, "but got", sources)
+	}
+
+	if _, err := client.LayeredTable(ctx, sources, LayerRequireAll); !errors.Is(err, ErrNoRecords) {
+		t.Error("Expected ErrNoRecords but got", err)
+	}
+
+	if _, err := client.LayeredTable(ctx, []string{"dns:missing.example.com"}, LayerMerge); !errors.Is(err, ErrNoRecords) {
+		t.Error("Expected ErrNoRecords but got", err)
+	}
+}
+
+type fetchErrorTestPair struct {
+	Source string
+	Err    error
+}
+
+func TestClientFetchErrors(t *testing.T) {
+	nameserver := startTestServer(t, "127.0.0.1:0", []string{`exists.example.com. 300 IN TXT "a=1"`})
+
+	failing, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	failingServer := &dns.Server{
+		Listener: failing,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, query *dns.Msg) {
+			response := new(dns.Msg)
+			response.SetRcode(query, dns.RcodeServerFailure)
+			w.WriteMsg(response)
+		}),
+	}
+	go failingServer.ActivateAndServe()
+	t.Cleanup(func() { failingServer.Shutdown() })
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error", err.Error())
+	}
+	closedAddress := closed.Addr().String()
+	closed.Close()
+
+	for _, testPair := range []fetchErrorTestPair{
+		{"dns://" + nameserver + "/missing.example.com", ErrNoRecords},
+		{"dns://" + failing.Addr().String() + "/exists.example.com", ErrServerFailure},
+		{"dns://" + closedAddress + "/exists.example.com?timeout=1s", ErrNetwork},
+		{"file:///nonexistent/records", ErrFileAccess},
+	} {
+		client := NewClient(Options{})
+		_, err := client.Table(context.Background(), testPair.Source)
+
+		if !errors.Is(err, testPair.Err) {
+			t.Error("Expected", testPair.Err, "but got", err, "for", testPair)
+		}
+
+		var fetchErr *FetchError
+		if !errors.As(err, &fetchErr) || fetchErr.Source != testPair.Source {
+			t.Error("Expected a FetchError for", testPair.Source, "but got", err)
+		}
+	}
+}
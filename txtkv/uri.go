@@ -0,0 +1,75 @@
+package txtkv
+
+// Parser for URIs
+// The one in net/url doesn't work properly for general URIs
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// URI is a parsed source URI.
+type URI struct {
+	Scheme    string
+	Authority string
+	// Path is percent-decoded.
+	Path string
+	// RawQuery is the query without the leading "?", still percent-encoded.
+	RawQuery string
+	// Params are the decoded query parameters, with lower case names.
+	Params url.Values
+	// Fragment is the fragment without the leading "#".
+	Fragment string
+}
+
+// https://tools.ietf.org/html/rfc3986
+var parser = regexp.MustCompile(`^(?P<scheme>[a-z][a-z0-9+.-]*):(?://(?P<authority>[^/?#]*))?(?P<path>[^#?]*)?(?:\?(?P<query>[^#]*))?(?:#(?P<fragment>.*))?$`)
+
+func parseURI(uri string) (*URI, error) {
+	matches := parser.FindStringSubmatch(uri)
+	if matches == nil {
+		return nil, fmt.Errorf("failed to parse \"%s\" as URI", uri)
+	}
+	result := &URI{
+		Scheme:    matches[1],
+		Authority: matches[2],
+		Path:      matches[3],
+		RawQuery:  matches[4],
+		Fragment:  matches[5],
+	}
+	var err error
+	result.Path, err = url.PathUnescape(result.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unencode path component \"%s\" of URI \"%s\": %w", result.Path, uri, err)
+	}
+	result.Params, err = parseParams(result.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unencode query component \"%s\" of URI \"%s\": %w", result.RawQuery, uri, err)
+	}
+	return result, nil
+}
+
+// Parameters can be separated by ";" (like in https://tools.ietf.org/html/rfc4501) as well as "&".
+// Parameter names are case-insensitive.
+func parseParams(query string) (url.Values, error) {
+	params := make(url.Values)
+	for _, param := range strings.FieldsFunc(query, func(c rune) bool { return c == '&' || c == ';' }) {
+		name, value := param, ""
+		if i := strings.IndexByte(param, '='); i >= 0 {
+			name, value = param[:i], param[i+1:]
+		}
+		name, err := url.QueryUnescape(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err = url.QueryUnescape(value)
+		if err != nil {
+			return nil, err
+		}
+		name = strings.ToLower(name)
+		params[name] = append(params[name], value)
+	}
+	return params, nil
+}
@@ -0,0 +1,43 @@
+package txtkv
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type parseURITestPair struct {
+	Input  string
+	Result *URI
+	Err    error
+}
+
+func TestParseURI(t *testing.T) {
+	for _, testPair := range []parseURITestPair{
+		{"", nil, errors.New("Not a URI")},
+		{"nope", nil, errors.New("Not a URI")},
+		// Examples from https://tools.ietf.org/html/rfc4501
+		{"dns:www.example.org.?clAsS=IN;tYpE=A", &URI{"dns", "", "www.example.org.", "clAsS=IN;tYpE=A", url.Values{"class": {"IN"}, "type": {"A"}}, ""}, nil},
+		{"dns:www.example.org", &URI{"dns", "", "www.example.org", "", url.Values{}, ""}, nil},
+		{"dns://192.168.1.1/ftp.example.org?type=A", &URI{"dns", "192.168.1.1", "/ftp.example.org", "type=A", url.Values{"type": {"A"}}, ""}, nil},
+		{"dns:world%20wide%20web.example%5c.domain.org?TYPE=TXT", &URI{"dns", "", "world wide web.example\\.domain.org", "TYPE=TXT", url.Values{"type": {"TXT"}}, ""}, nil},
+		{"dns://ns/foo?timeout=2s&dnssec", &URI{"dns", "ns", "/foo", "timeout=2s&dnssec", url.Values{"timeout": {"2s"}, "dnssec": {""}}, ""}, nil},
+		{"file:/tmp/records?format=json%2Fstrict#frag", &URI{"file", "", "/tmp/records", "format=json%2Fstrict", url.Values{"format": {"json/strict"}}, "frag"}, nil},
+		{"dns:foo?bad=%zz", nil, errors.New("Bad escape")},
+	} {
+		result, err := parseURI(testPair.Input)
+
+		if err != nil && testPair.Err == nil {
+			t.Error("Unexpected error", err.Error(), "for", testPair)
+		}
+
+		if err == nil && testPair.Err != nil {
+			t.Error("Expected error", testPair.Err.Error(), "not caught for", testPair)
+		}
+
+		if !reflect.DeepEqual(result, testPair.Result) {
+			t.Error("Expected", testPair.Result, "but got", result, "for", testPair)
+		}
+	}
+}